@@ -1,83 +1,117 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"goasteroids/sim"
 	"golang.org/x/image/colornames"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 	"image"
+	"image/color"
 	_ "image/png"
 	"math"
-	"math/rand"
 	"os"
 	"time"
 )
 
-const screenWidth = 1024
-const screenHeight = 768
+const maxFrameLength = 0.25
 
-type etype int
-
-const (
-	Ship       etype = 1
-	Asteroid   etype = 2
-	Projectile etype = 3
+var (
+	windowTitlePrefix = "Go Asteroids"
+	frames            = 0
+	second            = time.Tick(time.Second)
+	window            *pixelgl.Window
+	frameLength       float64
+	world             *sim.World
+	seed              int64
+	config            = sim.DefaultConfig()
+	recording         *sim.Replay
+	recordPath        string
+	weaponsPath       string
+	playback          *sim.Replay
+	shipSprite        *pixel.Sprite
+	asteroidSprite    *pixel.Sprite
+	fireballSprite    *pixel.Sprite
+	pickupSprites     = map[sim.PickupKind]*pixel.Sprite{}
+	textAtlas         *text.Atlas
+	shapes            = imdraw.New(nil)
 )
 
-type entity struct {
-	etype
-	x      float64
-	y      float64
-	dx     float64
-	dy     float64
-	radius float64
-	angle  float64
-	scale  float64
-	sprite *pixel.Sprite
+func loadImageFile(path string) (image.Image, *sim.AlphaMask, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return img, sim.NewAlphaMask(img), nil
 }
 
-func (e entity) separation(e2 entity) float64 {
-
-	return math.Sqrt(math.Pow(e.x-e2.x, 2) + math.Pow(e.y-e2.y, 2))
+func loadArt(path string) (image.Image, *sim.AlphaMask) {
 
-}
+	img, mask, err := loadImageFile(path)
+	if err != nil {
+		panic(err)
+	}
 
-func (e entity) collidesWith(e2 entity) bool {
+	return img, mask
 
-	return e.separation(e2) <= e.radius+e2.radius
+}
 
+// Pickups have no artwork of their own, so each gets a coloured ring with a
+// letter in the middle, drawn once at load time.
+var pickupLooks = map[sim.PickupKind]struct {
+	colour color.RGBA
+	letter string
+}{
+	sim.SpreadShot:    {colornames.Orange, "S"},
+	sim.RapidFire:     {colornames.Yellow, "R"},
+	sim.PiercingShots: {colornames.Magenta, "P"},
+	sim.ShieldRefill:  {colornames.Deepskyblue, "E"},
+	sim.ExtraLife:     {colornames.Lime, "L"},
 }
 
-func (e entity) velocity() float64 {
+var materialTints = map[sim.Material]color.RGBA{
+	sim.Iron:      colornames.Lightsteelblue,
+	sim.Ice:       colornames.Aqua,
+	sim.Explosive: colornames.Orangered,
+	sim.Magnetic:  colornames.Violet,
+}
 
-	return math.Sqrt(math.Pow(e.dx, 2) + math.Pow(e.dy, 2))
+func makePickupImage(colour color.RGBA, letter string) image.Image {
 
-}
+	const size = 2 * sim.PickupRadius
 
-var (
-	windowTitlePrefix = "Go Asteroids"
-	frames            = 0
-	second            = time.Tick(time.Second)
-	window            *pixelgl.Window
-	frameLength       float64
-	es                []entity
-	shipPic           pixel.Picture
-	asteroidPic       pixel.Picture
-	fireballPic       pixel.Picture
-	lastFire          time.Time
-)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
 
-func loadImageFile(path string) (image.Image, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			d := math.Hypot(float64(x)+0.5-size/2, float64(y)+0.5-size/2)
+			if d <= size/2 && d >= size/2-3 {
+				img.SetRGBA(x, y, colour)
+			}
+		}
 	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(colour),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(size/2-3, size/2+5),
 	}
-	return img, nil
+	d.DrawString(letter)
+
+	return img
+
 }
 
 func initiate() {
@@ -85,7 +119,7 @@ func initiate() {
 	var initError error
 
 	cfg := pixelgl.WindowConfig{
-		Bounds: pixel.R(0, 0, screenWidth, screenHeight),
+		Bounds: pixel.R(0, 0, sim.ScreenWidth, sim.ScreenHeight),
 		VSync:  true,
 	}
 
@@ -94,287 +128,270 @@ func initiate() {
 		panic(initError)
 	}
 
-	shipImage, initError := loadImageFile("ship.png")
-	if initError != nil {
-		panic(initError)
-	}
-	shipPic = pixel.PictureDataFromImage(shipImage)
+	art := make([]image.Image, 4, 4+len(sim.PickupKinds))
+	art[0], sim.Masks[sim.Ship] = loadArt("ship.png")
+	art[1], sim.Masks[sim.Asteroid] = loadArt("asteroid.png")
+	art[2], sim.Masks[sim.Projectile] = loadArt("fireball.png")
+	art[3] = makeParticleImage()
 
-	asteroidImage, initError := loadImageFile("asteroid.png")
-	if initError != nil {
-		panic(initError)
+	for _, kind := range sim.PickupKinds {
+		look := pickupLooks[kind]
+		art = append(art, makePickupImage(look.colour, look.letter))
 	}
-	asteroidPic = pixel.PictureDataFromImage(asteroidImage)
 
-	fireballImage, initError := loadImageFile("fireball.png")
-	if initError != nil {
-		panic(initError)
+	// everything is packed into one atlas so that a frame's sprites can all be
+	// drawn through a single batch
+	atlas, frames := packAtlas(art)
+
+	shipSprite = pixel.NewSprite(atlas, frames[0])
+	asteroidSprite = pixel.NewSprite(atlas, frames[1])
+	fireballSprite = pixel.NewSprite(atlas, frames[2])
+	particleSprite = pixel.NewSprite(atlas, frames[3])
+	for k, kind := range sim.PickupKinds {
+		pickupSprites[kind] = pixel.NewSprite(atlas, frames[4+k])
 	}
-	fireballPic = pixel.PictureDataFromImage(fireballImage)
-
-	es = []entity{{
-		etype:  Ship,
-		x:      float64(screenWidth / 2),
-		y:      float64(screenHeight / 2),
-		dx:     0,
-		dy:     0,
-		angle:  0.0,
-		radius: 30,
-		sprite: pixel.NewSprite(shipPic, shipPic.Bounds()),
-		scale:  0.2,
-	}}
-
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	for i := 1; i <= 20; i++ {
-
-		e := entity{
-			etype:  Asteroid,
-			x:      r.Float64() * screenWidth,
-			y:      r.Float64() * screenHeight,
-			dx:     r.Float64()*100 - 50,
-			dy:     r.Float64()*100 - 50,
-			angle:  r.Float64() * 2 * math.Pi,
-			sprite: pixel.NewSprite(asteroidPic, asteroidPic.Bounds()),
-			scale:  0.1,
-			radius: 45,
-		}
 
-		okPosition := true
-		for {
-			okPosition = true
-			for j := 0; j < i; j++ {
-				if e.collidesWith(es[j]) {
-					okPosition = false
-				}
-			}
-			if okPosition {
-				break
-			}
-			e.x = r.Float64() * screenWidth
-			e.y = r.Float64() * screenHeight
-		}
+	spriteBatch = pixel.NewBatch(&pixel.TrianglesData{}, atlas)
 
-		es = append(es, e)
+	textAtlas = text.NewAtlas(basicfont.Face7x13, text.ASCII)
 
-	}
+	loadScores()
+	newGame()
 
 }
 
-func game() {
-
-	initiate()
-
-	for !window.Closed() {
+func readInput() sim.InputState {
+
+	return sim.InputState{
+		RotateLeft:  window.Pressed(pixelgl.KeyLeft),
+		RotateRight: window.Pressed(pixelgl.KeyRight),
+		Thrust:      window.Pressed(pixelgl.KeyW),
+		Reverse:     window.Pressed(pixelgl.KeyS),
+		StrafeLeft:  window.Pressed(pixelgl.KeyA),
+		StrafeRight: window.Pressed(pixelgl.KeyD),
+		Fire:        window.Pressed(pixelgl.KeySpace),
+		Defend:      window.Pressed(pixelgl.KeyLeftShift),
+		NextWeapon:  window.Pressed(pixelgl.KeyE),
+		PrevWeapon:  window.Pressed(pixelgl.KeyQ),
+	}
 
-		frameStart := time.Now()
+}
 
-		if window.Pressed(pixelgl.KeyLeft) {
-			es[0].angle += 2 * frameLength
-		}
-		if window.Pressed(pixelgl.KeyRight) {
-			es[0].angle -= 2 * frameLength
-		}
-		if window.Pressed(pixelgl.KeyW) {
-			es[0].dx -= 25 * math.Sin(es[0].angle)
-			es[0].dy += 25 * math.Cos(es[0].angle)
-		}
-		if window.Pressed(pixelgl.KeyS) {
-			es[0].dx += 25 * math.Sin(es[0].angle)
-			es[0].dy -= 25 * math.Cos(es[0].angle)
-		}
-		if window.Pressed(pixelgl.KeyA) {
-			es[0].dx -= 25 * math.Cos(es[0].angle)
-			es[0].dy -= 25 * math.Sin(es[0].angle)
-		}
-		if window.Pressed(pixelgl.KeyD) {
-			es[0].dx += 25 * math.Cos(es[0].angle)
-			es[0].dy += 25 * math.Sin(es[0].angle)
-		}
+func spriteFor(e sim.Entity) *pixel.Sprite {
+
+	switch e.EntityType {
+	case sim.Ship:
+		return shipSprite
+	case sim.Asteroid:
+		return asteroidSprite
+	case sim.Pickup:
+		return pickupSprites[e.Pickup]
+	default:
+		return fireballSprite
+	}
 
-		if window.Pressed(pixelgl.KeySpace) {
+}
 
-			if time.Since(lastFire).Seconds() > 0.2 {
+// ghostOffsets lists where along one axis to draw an entity so that anything
+// hanging off one edge of the screen also shows at the opposite edge. There are
+// never more than three, so they come back in an array to spare the allocation.
+func ghostOffsets(pos, reach, screen, field float64) ([3]float64, int) {
 
-				lastFire = time.Now()
+	offsets, n := [3]float64{0}, 1
+	if pos-reach < 0 {
+		offsets[n] = field
+		n++
+	}
+	if pos+reach > screen {
+		offsets[n] = -field
+		n++
+	}
+	return offsets, n
 
-				projDx := -math.Sin(es[0].angle)
-				projDy := math.Cos(es[0].angle)
+}
 
-				es = append(es, entity{
-					etype:  Projectile,
-					x:      es[0].x + es[0].radius*projDx,
-					y:      es[0].y + es[0].radius*projDy,
-					dx:     500 * projDx,
-					dy:     500 * projDy,
-					angle:  es[0].angle,
-					radius: 10,
-					sprite: pixel.NewSprite(fireballPic, fireballPic.Bounds()),
-					scale:  0.05,
-				})
+func draw(w *sim.World, alpha float64) {
 
-			}
+	drawStarfield()
+	queueParticles()
 
-		}
+	queueEntities(w, alpha)
+	queueLives(w)
 
-		var newAsteroids []entity
+	flushDrawList()
 
-		for i := 0; i < len(es); {
+	if ship := w.Entities[0]; w.ShieldUp {
+		x, y, _ := ship.Lerp(alpha)
+		drawShield(pixel.V(x, y), ship.Radius, w.ShieldEnergy)
+	}
 
-			removeI := false
-			splitJ := 0
+	drawEffects()
 
-			for j := 1; j < len(es); j++ {
+	shapes.Draw(window)
+	shapes.Clear()
 
-				if i == j || es[j].etype == Projectile && (i == 0 || es[i].etype == Asteroid) {
-					continue
-				}
+	drawHUD(w)
 
-				if es[i].collidesWith(es[j]) {
+	if wave, ok := w.ShowWaveBanner(); ok && !w.GameOver {
+		drawBanner(fmt.Sprintf("WAVE %d", wave))
+	}
 
-					if es[i].etype == Projectile && es[j].etype == Asteroid {
+}
 
-						removeI = true
-						splitJ = j
+// drawOutline draws a vector asteroid as its jagged outline.
+func drawOutline(pos pixel.Vec, angle float64, e sim.Entity) {
 
-					} else {
+	shapes.Color = colornames.White
+	if e.Material != sim.Rock {
+		shapes.Color = materialTints[e.Material]
+	}
 
-						d := es[i].separation(es[j])
-						dx := es[i].x - es[j].x
-						dy := es[i].y - es[j].y
+	for _, p := range e.Shape {
+		r := p.Rotated(angle)
+		shapes.Push(pos.Add(pixel.V(r.X, r.Y)))
+	}
+	shapes.Polygon(2)
 
-						v1 := es[i].velocity()
-						v2 := es[j].velocity()
+}
 
-						es[i].dx = v2 * dx / d
-						es[i].dy = v2 * dy / d
+// drawSaucer outlines the classic flying saucer: a domed cabin on a wide rim.
+func drawSaucer(pos pixel.Vec, radius float64) {
 
-						es[j].dx = -v1 * dx / d
-						es[j].dy = -v1 * dy / d
-					}
+	shapes.Color = colornames.Lightsteelblue
 
-					continue
+	rim := []pixel.Vec{
+		{X: -radius, Y: 0},
+		{X: -radius * 0.5, Y: radius * 0.3},
+		{X: radius * 0.5, Y: radius * 0.3},
+		{X: radius, Y: 0},
+		{X: radius * 0.5, Y: -radius * 0.3},
+		{X: -radius * 0.5, Y: -radius * 0.3},
+		{X: -radius, Y: 0},
+		{X: radius, Y: 0},
+	}
+	for _, p := range rim {
+		shapes.Push(pos.Add(p))
+	}
+	shapes.Line(2)
 
-				}
+	cabin := []pixel.Vec{
+		{X: -radius * 0.5, Y: radius * 0.3},
+		{X: -radius * 0.3, Y: radius * 0.6},
+		{X: radius * 0.3, Y: radius * 0.6},
+		{X: radius * 0.5, Y: radius * 0.3},
+	}
+	for _, p := range cabin {
+		shapes.Push(pos.Add(p))
+	}
+	shapes.Line(2)
 
-			}
+}
 
-			if removeI {
+// drawShield rings the ship, fading as the shield's energy runs down.
+func drawShield(pos pixel.Vec, radius, energy float64) {
 
-				if es[splitJ].radius >= 20 {
+	shapes.Color = pixel.ToRGBA(colornames.Deepskyblue).Scaled(0.4 + 0.6*energy)
+	shapes.Push(pos)
+	shapes.Circle(radius*1.3, 3)
 
-					v := es[i].velocity()
-					dx := es[i].dx / v
-					dy := es[i].dy / v
+}
 
-					es[splitJ].dx = -dy * v * 2
-					es[splitJ].dy = dx * v * 2
-					es[splitJ].scale *= 0.75
-					es[splitJ].radius *= 0.75
+// drawBanner writes a message in large letters across the middle of the screen.
+func drawBanner(message string) {
 
-					newAsteroids = append(newAsteroids, entity{
-						etype:  Asteroid,
-						x:      es[splitJ].x,
-						y:      es[splitJ].y,
-						dx:     -es[splitJ].dx,
-						dy:     -es[splitJ].dy,
-						angle:  -es[splitJ].angle,
-						sprite: pixel.NewSprite(asteroidPic, asteroidPic.Bounds()),
-						scale:  es[splitJ].scale,
-						radius: es[splitJ].radius})
+	banner := text.New(pixel.ZV, textAtlas)
+	banner.Color = colornames.White
+	fmt.Fprint(banner, message)
 
-				} else {
+	banner.Draw(window, pixel.IM.
+		Moved(banner.Bounds().Center().Scaled(-1)).
+		Scaled(pixel.ZV, 4).
+		Moved(pixel.V(sim.ScreenWidth/2, sim.ScreenHeight/2)))
 
-					es[splitJ].radius = 0
+}
 
-				}
+func game() {
 
-				es = append(es[:i], es[i+1:]...)
+	initiate()
 
-			} else {
+	lastFrame := time.Now()
 
-				i++
+	for !window.Closed() {
 
-			}
+		frameStart := time.Now()
+		frameLength = frameStart.Sub(lastFrame).Seconds()
+		lastFrame = frameStart
 
+		if frameLength > maxFrameLength {
+			frameLength = maxFrameLength
 		}
 
-		es = append(es, newAsteroids...)
-
-		for i := 0; i < len(es); {
-			if es[i].etype == Asteroid && es[i].radius == 0 {
-				es = append(es[:i], es[i+1:]...)
-			} else {
-				i++
-			}
+		if window.JustPressed(pixelgl.KeyF3) {
+			showStats = !showStats
 		}
 
-		for i := range es {
-
-			es[i].x += es[i].dx * frameLength
-			es[i].y += es[i].dy * frameLength
-
-			if es[i].x < -50 {
-				es[i].x += screenWidth + 100
-			}
-			if es[i].y < -50 {
-				es[i].y += screenHeight + 100
-			}
-			if es[i].x > screenWidth+50 {
-				es[i].x -= screenWidth + 100
-			}
-			if es[i].y > screenHeight+50 {
-				es[i].y -= screenHeight + 100
-			}
-
-			v := es[i].velocity()
-			if es[i].etype == Ship {
-				if v > 256 {
-					es[i].dx *= 256 / v
-					es[i].dy *= 256 / v
-				} else {
-					es[i].dx *= 1 - frameLength
-					es[i].dy *= 1 - frameLength
-				}
-			} else if es[i].etype == Asteroid {
-				if v > 128 {
-					es[i].dx *= 128 / v
-					es[i].dy *= 128 / v
-				}
-			}
-
-		}
+		update()
 
 		window.Clear(colornames.Black)
-
-		for i := range es {
-
-			matrix := pixel.IM.
-				Rotated(pixel.ZV, es[i].angle).
-				Scaled(pixel.ZV, es[i].scale).
-				Moved(pixel.Vec{X: es[i].x, Y: es[i].y})
-
-			es[i].sprite.Draw(window, matrix)
-
-		}
+		drawState()
 
 		window.Update()
 
 		frames++
 		select {
 		case <-second:
-			window.SetTitle(fmt.Sprintf("%s | FPS: %d", windowTitlePrefix, frames))
+			fps = frames
 			frames = 0
 		default:
 		}
 
-		frameLength = time.Since(frameStart).Seconds()
+	}
 
+	if recordPath != "" {
+		if err := recording.Save(recordPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 	}
 }
 
 func main() {
 
+	replayPath := flag.String("replay", "", "play back the inputs recorded in this replay file")
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "random seed for the world")
+	flag.IntVar(&config.MaxShots, "max-shots", config.MaxShots, "most shots the ship can have in flight at once")
+	flag.IntVar(&config.ExtraLifeEvery, "extra-life-every", config.ExtraLifeEvery, "points needed for each extra life, 0 for none")
+	flag.Float64Var(&config.HyperspaceRisk, "hyperspace-risk", config.HyperspaceRisk, "chance of the ship exploding as it leaves hyperspace")
+	flag.Var(&config.Defence, "defence", "defensive action on shift: hyperspace or shield")
+	flag.Var(&config.Difficulty, "difficulty", "how hard the game is: easy, normal or hard")
+	flag.BoolVar(&config.VectorAsteroids, "vector", config.VectorAsteroids, "use jagged vector outlines for asteroids")
+	flag.BoolVar(&config.PixelCollision, "pixel-collision", config.PixelCollision, "collide sprites by their solid pixels instead of circles")
+	flag.BoolVar(&twinkle, "twinkle", twinkle, "make some of the background stars twinkle")
+	flag.StringVar(&weaponsPath, "weapons", "", "load weapon definitions from this JSON file")
+	flag.StringVar(&recordPath, "record", "", "save a replay of the last game played to this file on exit")
+	flag.Parse()
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			fixedSeed = true
+		}
+	})
+
+	if weaponsPath != "" {
+		var err error
+		config.Weapons, err = sim.LoadWeaponDefs(weaponsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *replayPath != "" {
+		var err error
+		playback, err = sim.LoadReplay(*replayPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	pixelgl.Run(game)
 
 }
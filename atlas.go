@@ -0,0 +1,157 @@
+package main
+
+import (
+	"github.com/faiface/pixel"
+	"goasteroids/sim"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+)
+
+const (
+	atlasWidth   = 2048
+	atlasPadding = 2
+)
+
+// packAtlas copies every image into one picture, in rows left to right, and
+// returns where each one ended up in it. Padding between them stops neighbours
+// bleeding into each other when sprites are scaled down.
+func packAtlas(imgs []image.Image) (*pixel.PictureData, []pixel.Rect) {
+
+	places := make([]image.Point, len(imgs))
+	x, y, rowHeight := 0, 0, 0
+
+	for k, img := range imgs {
+		size := img.Bounds().Size()
+		if x > 0 && x+size.X > atlasWidth {
+			x, y, rowHeight = 0, y+rowHeight+atlasPadding, 0
+		}
+		places[k] = image.Pt(x, y)
+		x += size.X + atlasPadding
+		if size.Y > rowHeight {
+			rowHeight = size.Y
+		}
+	}
+
+	height := y + rowHeight
+	sheet := image.NewRGBA(image.Rect(0, 0, atlasWidth, height))
+
+	// pictures have y pointing up, so each frame is flipped within the sheet
+	frames := make([]pixel.Rect, len(imgs))
+	for k, img := range imgs {
+		b := img.Bounds()
+		at := image.Rectangle{Min: places[k], Max: places[k].Add(b.Size())}
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				sheet.Set(at.Min.X+x, at.Min.Y+y, img.At(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+		frames[k] = pixel.R(float64(at.Min.X), float64(height-at.Max.Y), float64(at.Max.X), float64(height-at.Min.Y))
+	}
+
+	return pixel.PictureDataFromImage(sheet), frames
+
+}
+
+// drawItem is one sprite waiting to go into the frame's batch. A nil mask draws
+// it in its own colours.
+type drawItem struct {
+	sprite *pixel.Sprite
+	matrix pixel.Matrix
+	mask   color.Color
+}
+
+var (
+	drawList    []drawItem
+	spriteBatch *pixel.Batch
+)
+
+func queueSprite(sprite *pixel.Sprite, matrix pixel.Matrix, mask color.Color) {
+
+	drawList = append(drawList, drawItem{sprite, matrix, mask})
+
+}
+
+// queueEntities adds a sprite for everything in play to the draw list, plus ghosts
+// of anything hanging over an edge. Saucers and vector rocks have no sprite, so
+// they go to shapes instead. None of this touches the window, so it can be built
+// and measured without one.
+func queueEntities(w *sim.World, alpha float64) {
+
+	for i, e := range w.Entities {
+
+		if e.Hidden || i == 0 && w.Blinking() {
+			continue
+		}
+
+		// pickups flash for their last two seconds
+		if e.EntityType == sim.Pickup && e.TTL < 2 && int(e.TTL*8)%2 == 1 {
+			continue
+		}
+
+		x, y, angle := e.Lerp(alpha)
+
+		sprite := spriteFor(e)
+		reach := sprite.Frame().Size().Len() / 2 * e.Scale
+		if e.EntityType == sim.Saucer {
+			reach = e.Radius * 1.5
+		} else if len(e.Shape) > 0 {
+			reach = e.Radius
+		}
+
+		xs, nx := ghostOffsets(x, reach, sim.ScreenWidth, sim.PlayfieldWidth)
+		ys, ny := ghostOffsets(y, reach, sim.ScreenHeight, sim.PlayfieldHeight)
+
+		for _, ox := range xs[:nx] {
+			for _, oy := range ys[:ny] {
+
+				pos := pixel.Vec{X: x + ox, Y: y + oy}
+
+				if e.EntityType == sim.Saucer {
+					drawSaucer(pos, e.Radius)
+					continue
+				}
+
+				if len(e.Shape) > 0 {
+					drawOutline(pos, angle, e)
+					continue
+				}
+
+				matrix := pixel.IM.
+					Rotated(pixel.ZV, angle).
+					Scaled(pixel.ZV, e.Scale).
+					Moved(pos)
+
+				switch {
+				case e.EntityType == sim.Projectile && e.Owner != w.Entities[0].ID:
+					queueSprite(sprite, matrix, colornames.Lime)
+				case e.Mine:
+					queueSprite(sprite, matrix, colornames.Red)
+				case e.Homing > 0:
+					queueSprite(sprite, matrix, colornames.Orange)
+				case e.EntityType == sim.Asteroid && e.Material != sim.Rock:
+					queueSprite(sprite, matrix, materialTints[e.Material])
+				default:
+					queueSprite(sprite, matrix, nil)
+				}
+
+			}
+		}
+
+	}
+
+}
+
+// flushDrawList draws everything queued this frame in one batch, in the order it
+// was queued, and empties the list for the next frame.
+func flushDrawList() {
+
+	spriteBatch.Clear()
+	for _, item := range drawList {
+		item.sprite.DrawColorMask(spriteBatch, item.matrix, item.mask)
+	}
+	spriteBatch.Draw(window)
+
+	drawList = drawList[:0]
+
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"goasteroids/sim"
+	"math/rand"
+	"testing"
+)
+
+// BenchmarkQueueEntities measures building a frame's draw list during a heavy
+// fight. It needs no window, so the sprites are cut from a blank picture.
+func BenchmarkQueueEntities(b *testing.B) {
+
+	pic := pixel.MakePictureData(pixel.R(0, 0, 64, 64))
+	sprite := pixel.NewSprite(pic, pic.Bounds())
+	shipSprite, asteroidSprite, fireballSprite = sprite, sprite, sprite
+	for _, kind := range sim.PickupKinds {
+		pickupSprites[kind] = sprite
+	}
+
+	for _, n := range []int{1000, 5000, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+
+			w := sim.NewWorld(1, sim.DefaultConfig())
+			r := rand.New(rand.NewSource(1))
+			for k := 0; k < n; k++ {
+				e := sim.Entity{EntityType: sim.Asteroid, Radius: 45, Scale: 0.1, Material: sim.Material(r.Intn(5))}
+				if k%2 == 0 {
+					e = sim.Entity{EntityType: sim.Projectile, Radius: 10, Scale: 0.05}
+				}
+				e.X = r.Float64()*sim.PlayfieldWidth - 50
+				e.Y = r.Float64()*sim.PlayfieldHeight - 50
+				e.Angle = r.Float64() * 6
+				w.Spawn(e)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				drawList = drawList[:0]
+				queueEntities(w, 0.5)
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/faiface/pixel"
+	"goasteroids/sim"
+	"golang.org/x/image/colornames"
+	"image/color"
+)
+
+// effect is an expanding ring drawn where something happened, fading as it grows,
+// or a line from pos to end that simply fades.
+type effect struct {
+	pos      pixel.Vec
+	end      pixel.Vec
+	line     bool
+	age      float64
+	lifetime float64
+	from     float64
+	to       float64
+	colour   color.RGBA
+}
+
+var effects []effect
+
+func startEffects(events []sim.Event) {
+
+	for _, ev := range events {
+
+		pos := pixel.V(ev.X, ev.Y)
+
+		switch ev.Kind {
+		case sim.ShipDestroyed:
+			effects = append(effects, effect{pos: pos, lifetime: 0.8, from: 10, to: 90, colour: colornames.Orange})
+		case sim.HyperspaceOut:
+			effects = append(effects, effect{pos: pos, lifetime: 0.4, from: 50, to: 0, colour: colornames.Skyblue})
+		case sim.HyperspaceIn:
+			effects = append(effects, effect{pos: pos, lifetime: 0.4, from: 0, to: 50, colour: colornames.Skyblue})
+		case sim.Explosion:
+			effects = append(effects, effect{pos: pos, lifetime: 0.5, from: 20, to: 140, colour: colornames.Orangered})
+		case sim.LaserFired:
+			effects = append(effects, effect{pos: pos, end: pixel.V(ev.X2, ev.Y2), line: true, lifetime: 0.1, from: 3, to: 1, colour: colornames.Red})
+		}
+
+	}
+
+}
+
+func updateEffects(dt float64) {
+
+	live := effects[:0]
+	for _, fx := range effects {
+		fx.age += dt
+		if fx.age < fx.lifetime {
+			live = append(live, fx)
+		}
+	}
+	effects = live
+
+}
+
+func drawEffects() {
+
+	for _, fx := range effects {
+
+		t := fx.age / fx.lifetime
+		size := fx.from + (fx.to-fx.from)*t
+		if size <= 0 {
+			continue
+		}
+
+		shapes.Color = pixel.ToRGBA(fx.colour).Scaled(1 - t)
+
+		if fx.line {
+			shapes.Push(fx.pos, fx.end)
+			shapes.Line(size)
+			continue
+		}
+
+		shapes.Push(fx.pos)
+		shapes.Circle(size, 2)
+
+	}
+
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"goasteroids/sim"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	highScoresVersion = 1
+	highScoreEntries  = 10
+	initialsLength    = 3
+)
+
+type HighScore struct {
+	Initials string    `json:"initials"`
+	Score    int       `json:"score"`
+	Wave     int       `json:"wave"`
+	Date     time.Time `json:"date"`
+}
+
+// HighScores keeps a separate table for each game mode and difficulty, since a
+// score with a shield on easy can't fairly be ranked against hyperspace on hard.
+type HighScores struct {
+	Version int                    `json:"version"`
+	Tables  map[string][]HighScore `json:"tables"`
+}
+
+// defaultTable is what a table starts as before anyone has beaten it.
+func defaultTable() []HighScore {
+
+	table := make([]HighScore, highScoreEntries)
+	for k := range table {
+		table[k] = HighScore{Initials: "ACE", Score: (highScoreEntries - k) * 1000, Wave: 1}
+	}
+	return table
+
+}
+
+// tableKey names the table a game's scores go in.
+func tableKey(config sim.Config) string {
+
+	key := fmt.Sprintf("%s/%s", config.Defence, config.Difficulty)
+	if config.VectorAsteroids {
+		key += "/vector"
+	}
+	return key
+
+}
+
+func highScoresPath() (string, error) {
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goasteroids", "highscores.json"), nil
+
+}
+
+// loadHighScores never fails: a missing, damaged or out of date file just means
+// starting again from the default tables, and any entries that don't make sense
+// are dropped rather than spoiling the rest. A file that can't be read is moved
+// aside first, so the next save can't destroy scores that might be recovered or
+// that a newer version of the game wrote.
+func loadHighScores(path string) *HighScores {
+
+	scores := &HighScores{Version: highScoresVersion, Tables: map[string][]HighScore{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "warning: can't read high scores:", err)
+		}
+		return scores
+	}
+
+	var saved HighScores
+	if err := json.Unmarshal(data, &saved); err != nil {
+		setAside(path, err.Error())
+		return scores
+	}
+	if saved.Version != highScoresVersion {
+		setAside(path, fmt.Sprintf("version %d, want %d", saved.Version, highScoresVersion))
+		return scores
+	}
+
+	for key, table := range saved.Tables {
+		var valid []HighScore
+		for _, s := range table {
+			if s.Score >= 0 && validInitials(s.Initials) {
+				valid = append(valid, s)
+			}
+		}
+		scores.Tables[key] = rank(valid)
+	}
+
+	return scores
+
+}
+
+// setAside renames an unreadable file out of the way. Each copy is stamped with
+// the time it was found, so a second bad file can't replace the first.
+func setAside(path, reason string) {
+
+	stamp := time.Now().Format("20060102-150405")
+	bad := fmt.Sprintf("%s.%s.bad", path, stamp)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(bad); os.IsNotExist(err) {
+			break
+		}
+		bad = fmt.Sprintf("%s.%s-%d.bad", path, stamp, n)
+	}
+
+	if err := os.Rename(path, bad); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: high scores in %s unreadable (%s) and can't be moved aside: %v\n", path, reason, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "warning: high scores in %s unreadable (%s), moved to %s\n", path, reason, bad)
+
+}
+
+func validInitials(initials string) bool {
+
+	if len(initials) != initialsLength {
+		return false
+	}
+	for _, c := range initials {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+
+}
+
+// rank sorts a table best first, keeping earlier entries ahead of later ties,
+// and cuts it down to size.
+func rank(table []HighScore) []HighScore {
+
+	sort.SliceStable(table, func(i, j int) bool { return table[i].Score > table[j].Score })
+	if len(table) > highScoreEntries {
+		table = table[:highScoreEntries]
+	}
+	return table
+
+}
+
+func (h *HighScores) table(key string) []HighScore {
+
+	table, ok := h.Tables[key]
+	if !ok {
+		return defaultTable()
+	}
+	return table
+
+}
+
+// qualifies reports whether a score is good enough to go in the table.
+func (h *HighScores) qualifies(key string, score int) bool {
+
+	table := h.table(key)
+	return score > 0 && (len(table) < highScoreEntries || score > table[len(table)-1].Score)
+
+}
+
+func (h *HighScores) add(key string, entry HighScore) {
+
+	h.Tables[key] = rank(append(h.table(key), entry))
+
+}
+
+// save writes the tables to a temporary file first and then moves it into place,
+// so a crash part way through can't leave a half-written file behind.
+func (h *HighScores) save(path string) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+
+}
+
+// initialsEntry is the arcade-style picker: up and down roll the letter under the
+// cursor, left and right move between letters, and typing a letter sets it and
+// moves on.
+type initialsEntry struct {
+	letters [initialsLength]byte
+	cursor  int
+}
+
+func newInitialsEntry() initialsEntry {
+
+	return initialsEntry{letters: [initialsLength]byte{'A', 'A', 'A'}}
+
+}
+
+func (e *initialsEntry) roll(by int) {
+
+	e.letters[e.cursor] = byte('A' + (int(e.letters[e.cursor]-'A')+by+26)%26)
+
+}
+
+func (e *initialsEntry) move(by int) {
+
+	e.cursor = (e.cursor + by + initialsLength) % initialsLength
+
+}
+
+func (e *initialsEntry) typed(s string) {
+
+	for _, c := range strings.ToUpper(s) {
+		if c >= 'A' && c <= 'Z' {
+			e.letters[e.cursor] = byte(c)
+			if e.cursor < initialsLength-1 {
+				e.cursor++
+			}
+		}
+	}
+
+}
+
+// display shows the initials spaced out, with the letter being set in brackets.
+func (e initialsEntry) display() string {
+
+	var b strings.Builder
+	for k, c := range e.letters {
+		if k == e.cursor {
+			fmt.Fprintf(&b, " [%c] ", c)
+		} else {
+			fmt.Fprintf(&b, "  %c  ", c)
+		}
+	}
+	return b.String()
+
+}
+
+func (e initialsEntry) String() string {
+
+	return string(e.letters[:])
+
+}
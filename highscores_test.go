@@ -0,0 +1,90 @@
+package main
+
+import (
+	"goasteroids/sim"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHighScoresRoundTrip(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "goasteroids", "highscores.json")
+	key := tableKey(sim.DefaultConfig())
+
+	scores := loadHighScores(path)
+	if !scores.qualifies(key, 5500) || scores.qualifies(key, 500) {
+		t.Fatal("default table ranks scores wrongly")
+	}
+
+	scores.add(key, HighScore{Initials: "BOB", Score: 5500, Wave: 3})
+	if err := scores.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	table := loadHighScores(path).table(key)
+	if len(table) != highScoreEntries || table[5].Initials != "BOB" {
+		t.Fatalf("reloaded table %v", table)
+	}
+
+}
+
+func TestUnreadableHighScoresAreMovedAside(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "highscores.json")
+	contents := []string{
+		"{\"version\": 1, \"tab",
+		"{\"version\": 99, \"tables\": {}}",
+	}
+
+	// both go to the same place, so the second must not replace the first
+	for _, content := range contents {
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		scores := loadHighScores(path)
+		if len(scores.Tables) != 0 {
+			t.Errorf("%q: got tables %v, want defaults", content, scores.Tables)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%q: unreadable file left in place", content)
+		}
+
+	}
+
+	kept, err := filepath.Glob(path + ".*.bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, name := range kept {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[string(data)] = true
+	}
+	for _, content := range contents {
+		if !found[content] {
+			t.Errorf("%q: original not kept aside, found %v", content, kept)
+		}
+	}
+
+}
+
+func TestInvalidHighScoreEntriesAreDropped(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "highscores.json")
+	content := `{"version": 1, "tables": {"a": [{"initials": "zz", "score": 3}, {"initials": "ABC", "score": -1}, {"initials": "XYZ", "score": 9}]}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table := loadHighScores(path).table("a")
+	if len(table) != 1 || table[0].Initials != "XYZ" {
+		t.Fatalf("got %v, want only XYZ", table)
+	}
+
+}
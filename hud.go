@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/text"
+	"goasteroids/sim"
+	"golang.org/x/image/colornames"
+	"image/color"
+	"sort"
+)
+
+const (
+	hudMargin = 16
+	barWidth  = 120
+	barHeight = 8
+)
+
+var (
+	showStats = false
+	fps       = 0
+)
+
+var powerUpLabels = map[sim.PickupKind]string{
+	sim.SpreadShot:    "SPREAD",
+	sim.RapidFire:     "RAPID",
+	sim.PiercingShots: "PIERCE",
+}
+
+// hudText writes a line of text with its top left corner at pos.
+func hudText(pos pixel.Vec, scale float64, colour color.Color, format string, args ...interface{}) {
+
+	t := text.New(pixel.ZV, textAtlas)
+	t.Color = colour
+	fmt.Fprintf(t, format, args...)
+
+	t.Draw(window, pixel.IM.
+		Moved(pixel.V(0, -t.Bounds().Max.Y)).
+		Scaled(pixel.ZV, scale).
+		Moved(pos))
+
+}
+
+func textWidth(s string) float64 {
+
+	t := text.New(pixel.ZV, textAtlas)
+	fmt.Fprint(t, s)
+	return t.Bounds().W()
+
+}
+
+// drawBar draws an outlined bar along from pos, filled to the given fraction.
+func drawBar(pos pixel.Vec, fill float64, colour color.Color) {
+
+	shapes.Color = colour
+	shapes.Push(pos, pos.Add(pixel.V(barWidth*fill, barHeight)))
+	shapes.Rectangle(0)
+
+	shapes.Color = colornames.Gray
+	shapes.Push(pos, pos.Add(pixel.V(barWidth, barHeight)))
+	shapes.Rectangle(1)
+
+}
+
+// queueLives adds a small ship under the score for every life left. It goes in
+// the frame's one batch along with everything else.
+func queueLives(w *sim.World) {
+
+	for k := 0; k < w.Lives; k++ {
+		queueSprite(shipSprite, pixel.IM.
+			Scaled(pixel.ZV, 0.07).
+			Moved(pixel.V(hudMargin+12+float64(k)*30, sim.ScreenHeight-hudMargin-48)), nil)
+	}
+
+}
+
+func drawHUD(w *sim.World) {
+
+	hudText(pixel.V(hudMargin, sim.ScreenHeight-hudMargin), 2, colornames.White, "%08d", w.Score)
+
+	wave := fmt.Sprintf("WAVE %d", w.Wave)
+	hudText(pixel.V(sim.ScreenWidth-hudMargin-textWidth(wave)*2, sim.ScreenHeight-hudMargin), 2, colornames.White, "%s", wave)
+
+	// weapon and defence status along the bottom left
+	bottom := pixel.V(hudMargin, hudMargin)
+
+	if w.Config.Defence == sim.ShieldDefence {
+		drawBar(bottom, w.ShieldEnergy, colornames.Deepskyblue)
+		hudText(bottom.Add(pixel.V(barWidth+8, barHeight+1)), 1, colornames.Deepskyblue, "SHIELD")
+		bottom.Y += barHeight + 8
+	}
+
+	wpn := w.Weapons[w.CurrentWeapon]
+	if wpn.Def().Heat > 0 {
+		heat := colornames.Orange
+		if !wpn.Ready() {
+			heat = colornames.Red
+		}
+		drawBar(bottom, wpn.Heat(), heat)
+		hudText(bottom.Add(pixel.V(barWidth+8, barHeight+1)), 1, heat, "HEAT")
+		bottom.Y += barHeight + 8
+	}
+
+	name := wpn.Def().Name
+	if wpn.Def().Ammo > 0 {
+		name = fmt.Sprintf("%s x%d", name, wpn.Ammo())
+	}
+	hudText(bottom.Add(pixel.V(0, 13*2)), 2, colornames.White, "%s", name)
+
+	shapes.Draw(window)
+	shapes.Clear()
+
+	// power-ups count down along the bottom right, in a steady order
+	var active []sim.PickupKind
+	for kind, left := range w.PowerUps {
+		if left > 0 && powerUpLabels[kind] != "" {
+			active = append(active, kind)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i] < active[j] })
+
+	for k, kind := range active {
+		hudText(pixel.V(sim.ScreenWidth-hudMargin-120, hudMargin+float64(k+1)*16), 1, pickupLooks[kind].colour,
+			"%-6s %4.1f", powerUpLabels[kind], w.PowerUps[kind])
+	}
+
+	if showStats {
+		hudText(pixel.V(hudMargin, sim.ScreenHeight-hudMargin-72), 1, colornames.Lime, "FPS %d  ENTITIES %d", fps, len(w.Entities))
+	}
+
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"github.com/faiface/pixel"
+	"goasteroids/sim"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// maxParticles caps how many particles can be alive at once. Once the pool is full
+// new particles take over the oldest slots, so a busy screen can't slow drawing.
+const maxParticles = 2000
+
+type particle struct {
+	pos      pixel.Vec
+	vel      pixel.Vec
+	age      float64
+	lifetime float64
+	drag     float64
+	size     float64
+	from     color.RGBA
+	to       color.RGBA
+}
+
+type emitterKind int
+
+const (
+	Burst emitterKind = 1
+	Cone  emitterKind = 2
+	Trail emitterKind = 3
+)
+
+// emitter describes a spray of particles. A burst goes out in every direction, a
+// cone within spread radians either side of a direction, and a trail is strewn
+// along a line with only a little drift of its own.
+type emitter struct {
+	kind     emitterKind
+	count    int
+	speed    float64
+	spread   float64
+	lifetime float64
+	drag     float64
+	size     float64
+	from     color.RGBA
+	to       color.RGBA
+}
+
+var (
+	exhaust  = emitter{kind: Cone, count: 2, speed: 180, spread: 0.3, lifetime: 0.35, drag: 2, size: 4, from: colornames.Yellow, to: colornames.Red}
+	sparks   = emitter{kind: Cone, count: 8, speed: 220, spread: 0.8, lifetime: 0.3, drag: 4, size: 3, from: colornames.White, to: colornames.Orange}
+	debris   = emitter{kind: Burst, count: 24, speed: 90, lifetime: 1.2, drag: 0.8, size: 4, from: colornames.Lightgray, to: colornames.Dimgray}
+	wreckage = emitter{kind: Burst, count: 60, speed: 160, lifetime: 1.5, drag: 1, size: 5, from: colornames.Yellow, to: colornames.Orangered}
+	smoke    = emitter{kind: Trail, count: 3, speed: 10, lifetime: 0.6, drag: 1, size: 5, from: colornames.Gray, to: colornames.Black}
+)
+
+var (
+	particles      [maxParticles]particle
+	nextParticle   int
+	particleSprite *pixel.Sprite
+	// particles are cosmetic, so they draw from their own source rather than the
+	// world's and can never change how a game plays out
+	particleRand *rand.Rand
+)
+
+// makeParticleImage draws the soft white dot every particle is tinted from.
+func makeParticleImage() image.Image {
+
+	const size = 8
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			d := math.Hypot(float64(x)+0.5-size/2, float64(y)+0.5-size/2) / (size / 2)
+			if d < 1 {
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, uint8(255 * (1 - d*d))})
+			}
+		}
+	}
+
+	return img
+
+}
+
+func initParticles(seed int64) {
+
+	particleRand = rand.New(rand.NewSource(seed))
+
+}
+
+// emit sprays the emitter's particles from pos. For a cone, dir is the way it
+// points; for a trail, the particles are strewn from pos to dir.
+func (em emitter) emit(pos, dir pixel.Vec) {
+
+	for k := 0; k < em.count; k++ {
+
+		start := pos
+		var angle float64
+
+		switch em.kind {
+		case Burst:
+			angle = particleRand.Float64() * 2 * math.Pi
+		case Cone:
+			angle = dir.Angle() + (particleRand.Float64()*2-1)*em.spread
+		case Trail:
+			start = pos.Add(dir.Sub(pos).Scaled(particleRand.Float64()))
+			angle = particleRand.Float64() * 2 * math.Pi
+		}
+
+		speed := em.speed * (0.5 + particleRand.Float64())
+
+		particles[nextParticle] = particle{
+			pos:      start,
+			vel:      pixel.V(math.Cos(angle), math.Sin(angle)).Scaled(speed),
+			lifetime: em.lifetime * (0.7 + 0.6*particleRand.Float64()),
+			drag:     em.drag,
+			size:     em.size,
+			from:     em.from,
+			to:       em.to,
+		}
+		nextParticle = (nextParticle + 1) % maxParticles
+
+	}
+
+}
+
+func startParticles(w *sim.World) {
+
+	for _, ev := range w.Events {
+
+		pos := pixel.V(ev.X, ev.Y)
+		dir := pixel.V(ev.X2-ev.X, ev.Y2-ev.Y)
+
+		switch ev.Kind {
+		case sim.Thrusting:
+			exhaust.emit(pos, dir)
+		case sim.ShotHit:
+			if dir == pixel.ZV {
+				sparks.emit(pos, pixel.V(0, 1))
+				continue
+			}
+			sparks.emit(pos, dir)
+		case sim.AsteroidSplit:
+			debris.emit(pos, pixel.ZV)
+		case sim.ShipDestroyed:
+			wreckage.emit(pos, pixel.ZV)
+		}
+
+	}
+
+	// missiles leave a smoke trail over the ground they covered this step
+	for _, e := range w.Entities {
+		if e.Homing > 0 && !e.Dead {
+			from := pixel.V(e.X-sim.WrapDelta(e.X-e.Px, sim.PlayfieldWidth), e.Y-sim.WrapDelta(e.Y-e.Py, sim.PlayfieldHeight))
+			smoke.emit(from, pixel.V(e.X, e.Y))
+		}
+	}
+
+}
+
+func updateParticles(dt float64) {
+
+	for k := range particles {
+
+		p := &particles[k]
+		if p.age >= p.lifetime {
+			continue
+		}
+
+		p.age += dt
+		p.vel = p.vel.Scaled(math.Max(0, 1-p.drag*dt))
+		p.pos = p.pos.Add(p.vel.Scaled(dt))
+
+	}
+
+}
+
+func queueParticles() {
+
+	for _, p := range particles {
+
+		if p.age >= p.lifetime {
+			continue
+		}
+
+		t := p.age / p.lifetime
+		colour := pixel.ToRGBA(p.from).Scaled(1 - t).Add(pixel.ToRGBA(p.to).Scaled(t)).Scaled(1 - t)
+
+		matrix := pixel.IM.
+			Scaled(pixel.ZV, p.size/particleSprite.Frame().W()).
+			Moved(p.pos)
+
+		queueSprite(particleSprite, matrix, colour)
+
+	}
+
+}
@@ -0,0 +1,145 @@
+package sim
+
+import "math"
+
+const cellSize = 128
+
+// spatialHash is a uniform grid laid over the wrapping playfield. Each entity is
+// filed under every cell its bounding box touches, with cells past one edge
+// wrapping round to the other, so only entities sharing a cell need a full test.
+type spatialHash struct {
+	cols       int
+	rows       int
+	cellWidth  float64
+	cellHeight float64
+	cells      [][]int
+	spans      []cellRange
+	seen       []int
+	found      [][2]int
+}
+
+// cellRange is the block of cells an entity was filed under, which may run off
+// one edge and carry on from the other.
+type cellRange struct {
+	col, cols int
+	row, rows int
+}
+
+func newSpatialHash(size float64) *spatialHash {
+
+	cols := int(math.Max(1, math.Floor(PlayfieldWidth/size)))
+	rows := int(math.Max(1, math.Floor(PlayfieldHeight/size)))
+
+	return &spatialHash{
+		cols:       cols,
+		rows:       rows,
+		cellWidth:  PlayfieldWidth / float64(cols),
+		cellHeight: PlayfieldHeight / float64(rows),
+		cells:      make([][]int, cols*rows),
+	}
+
+}
+
+// cellSpan returns the first cell and number of cells covered between lo and hi,
+// capped at the whole axis for anything wider than the playfield.
+func cellSpan(lo, hi, cellLength float64, count int) (int, int) {
+
+	first := int(math.Floor(lo / cellLength))
+	last := int(math.Floor(hi / cellLength))
+
+	n := last - first + 1
+	if n > count {
+		n = count
+	}
+
+	return first, n
+
+}
+
+func wrapIndex(i, n int) int {
+
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+
+}
+
+// build files every entity by the area it could sweep through in the next dt.
+func (h *spatialHash) build(es []Entity, dt float64) {
+
+	for c := range h.cells {
+		h.cells[c] = h.cells[c][:0]
+	}
+	h.spans = h.spans[:0]
+
+	for i, e := range es {
+
+		if e.Dead || e.Hidden {
+			h.spans = append(h.spans, cellRange{})
+			continue
+		}
+
+		x := e.X + 50
+		y := e.Y + 50
+		dx := e.Dx * dt
+		dy := e.Dy * dt
+
+		reach := e.reach()
+
+		var r cellRange
+		r.col, r.cols = cellSpan(x+math.Min(0, dx)-reach, x+math.Max(0, dx)+reach, h.cellWidth, h.cols)
+		r.row, r.rows = cellSpan(y+math.Min(0, dy)-reach, y+math.Max(0, dy)+reach, h.cellHeight, h.rows)
+		h.spans = append(h.spans, r)
+
+		h.visit(r, func(c int) { h.cells[c] = append(h.cells[c], i) })
+
+	}
+
+}
+
+// visit calls f with the index of every cell in the range.
+func (h *spatialHash) visit(r cellRange, f func(c int)) {
+
+	for v := 0; v < r.rows; v++ {
+		row := wrapIndex(r.row+v, h.rows) * h.cols
+		for u := 0; u < r.cols; u++ {
+			f(row + wrapIndex(r.col+u, h.cols))
+		}
+	}
+
+}
+
+// pairs returns each pair of entity indices that share at least one cell exactly
+// once, lower index first. Each entity only looks for partners after it, stamping
+// them as it goes so that a partner met again in another shared cell is skipped,
+// and since the order depends on nothing but the entities, collisions resolve the
+// same way every run. The slice returned is reused by the next call.
+func (h *spatialHash) pairs() [][2]int {
+
+	h.found = h.found[:0]
+
+	h.seen = h.seen[:0]
+	for range h.spans {
+		h.seen = append(h.seen, -1)
+	}
+
+	for i, r := range h.spans {
+
+		// cells are filled in index order, so the partners after i are at the end
+		h.visit(r, func(c int) {
+			cell := h.cells[c]
+			for k := len(cell) - 1; k >= 0 && cell[k] > i; k-- {
+				if j := cell[k]; h.seen[j] != i {
+					h.seen[j] = i
+					h.found = append(h.found, [2]int{i, j})
+				}
+			}
+		})
+
+	}
+
+	return h.found
+
+}
@@ -0,0 +1,156 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// scatter places n entities at random over the whole playfield, wrap margins
+// included.
+func scatter(n int, maxRadius float64) []Entity {
+
+	r := rand.New(rand.NewSource(1))
+	es := make([]Entity, n)
+	for i := range es {
+		es[i] = Entity{
+			X:      r.Float64()*PlayfieldWidth - 50,
+			Y:      r.Float64()*PlayfieldHeight - 50,
+			Radius: 5 + r.Float64()*(maxRadius-5),
+		}
+	}
+	return es
+
+}
+
+func naivePairs(es []Entity) map[[2]int]bool {
+
+	found := map[[2]int]bool{}
+	for i := range es {
+		for j := i + 1; j < len(es); j++ {
+			if es[i].collidesWith(es[j]) {
+				found[[2]int{i, j}] = true
+			}
+		}
+	}
+	return found
+
+}
+
+func hashPairs(h *spatialHash, es []Entity) map[[2]int]bool {
+
+	h.build(es, 0)
+	found := map[[2]int]bool{}
+	for _, p := range h.pairs() {
+		if es[p[0]].collidesWith(es[p[1]]) {
+			found[p] = true
+		}
+	}
+	return found
+
+}
+
+func TestBroadphaseFindsSamePairsAsBruteForce(t *testing.T) {
+
+	es := scatter(500, 45)
+
+	// pairs that only touch across the wrapping edges, including a corner
+	es = append(es,
+		Entity{X: -40, Y: 300, Radius: 15},
+		Entity{X: ScreenWidth + 40, Y: 300, Radius: 15},
+		Entity{X: 500, Y: -45, Radius: 10},
+		Entity{X: 500, Y: ScreenHeight + 45, Radius: 10},
+		Entity{X: -45, Y: -45, Radius: 10},
+		Entity{X: ScreenWidth + 45, Y: ScreenHeight + 45, Radius: 10},
+	)
+
+	want := naivePairs(es)
+	got := hashPairs(newSpatialHash(cellSize), es)
+
+	n := len(es)
+	for _, p := range [][2]int{{n - 6, n - 5}, {n - 4, n - 3}, {n - 2, n - 1}} {
+		if !want[p] {
+			t.Fatalf("entities %v should touch across the edge", p)
+		}
+	}
+
+	for p := range want {
+		if !got[p] {
+			t.Errorf("broadphase missed pair %v", p)
+		}
+	}
+	for p := range got {
+		if !want[p] {
+			t.Errorf("broadphase found pair %v that doesn't touch", p)
+		}
+	}
+
+}
+
+func TestBroadphaseListsEachPairOnce(t *testing.T) {
+
+	// the last two only overlap across the left and right edges
+	es := append(scatter(500, 45),
+		Entity{X: -40, Y: 300, Radius: 15},
+		Entity{X: ScreenWidth + 40, Y: 300, Radius: 15},
+	)
+
+	h := newSpatialHash(cellSize)
+	h.build(es, 0)
+	pairs := h.pairs()
+
+	listed := map[[2]int]bool{}
+	for _, p := range pairs {
+		if p[0] >= p[1] {
+			t.Fatalf("pair %v out of order", p)
+		}
+		if listed[p] {
+			t.Fatalf("pair %v listed twice", p)
+		}
+		listed[p] = true
+	}
+
+	n := len(es)
+	if !listed[[2]int{n - 2, n - 1}] {
+		t.Fatal("entities on opposite edges weren't paired")
+	}
+
+}
+
+var broadphaseSizes = []int{100, 1000, 10000}
+
+func BenchmarkBroadphase(b *testing.B) {
+
+	for _, n := range broadphaseSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			es := scatter(n, 25)
+			h := newSpatialHash(cellSize)
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				h.build(es, TickLength)
+				for _, p := range h.pairs() {
+					es[p[0]].collidesWith(es[p[1]])
+				}
+			}
+		})
+	}
+
+}
+
+func BenchmarkNaive(b *testing.B) {
+
+	for _, n := range broadphaseSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			es := scatter(n, 25)
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				for i := range es {
+					for j := i + 1; j < len(es); j++ {
+						es[i].collidesWith(es[j])
+					}
+				}
+			}
+		})
+	}
+
+}
@@ -0,0 +1,144 @@
+package sim
+
+import "fmt"
+
+type defence int
+
+// The pilot's defensive action is either a hyperspace jump or a shield.
+const (
+	HyperspaceDefence defence = 1
+	ShieldDefence     defence = 2
+)
+
+var defenceNames = map[string]defence{
+	"hyperspace": HyperspaceDefence,
+	"shield":     ShieldDefence,
+}
+
+func (d defence) String() string {
+
+	for name, d2 := range defenceNames {
+		if d2 == d {
+			return name
+		}
+	}
+	return "unknown"
+
+}
+
+func (d *defence) Set(name string) error {
+
+	d2, ok := defenceNames[name]
+	if !ok {
+		return fmt.Errorf("unknown defence %q, want hyperspace or shield", name)
+	}
+	*d = d2
+	return nil
+
+}
+
+type difficulty int
+
+const (
+	Easy   difficulty = 1
+	Normal difficulty = 2
+	Hard   difficulty = 3
+)
+
+var difficultyNames = map[string]difficulty{
+	"easy":   Easy,
+	"normal": Normal,
+	"hard":   Hard,
+}
+
+// difficultySpec scales a game's rules: how fast asteroids fly, how often saucers
+// come, and how many lives the pilot gets on top of the config's own.
+type difficultySpec struct {
+	speed   float64
+	saucers float64
+	lives   int
+}
+
+var difficultySpecs = map[difficulty]difficultySpec{
+	Easy:   {speed: 0.75, saucers: 1.5, lives: 2},
+	Normal: {speed: 1, saucers: 1, lives: 0},
+	Hard:   {speed: 1.3, saucers: 0.6, lives: -1},
+}
+
+// spec treats anything unknown, such as a config saved before difficulties
+// existed, as Normal.
+func (d difficulty) spec() difficultySpec {
+
+	spec, ok := difficultySpecs[d]
+	if !ok {
+		return difficultySpecs[Normal]
+	}
+	return spec
+
+}
+
+func (d difficulty) String() string {
+
+	for name, d2 := range difficultyNames {
+		if d2 == d {
+			return name
+		}
+	}
+	return "normal"
+
+}
+
+func (d *difficulty) Set(name string) error {
+
+	d2, ok := difficultyNames[name]
+	if !ok {
+		return fmt.Errorf("unknown difficulty %q, want easy, normal or hard", name)
+	}
+	*d = d2
+	return nil
+
+}
+
+// Config holds the tunable rules of a game. A world keeps the config it was
+// built with for its whole life.
+type Config struct {
+	MaxShots           int
+	Lives              int
+	Invulnerability    float64
+	ExtraLifeEvery     int
+	SaucerInterval     float64
+	HyperspaceCooldown float64
+	HyperspaceRisk     float64
+	Defence            defence
+	ShieldDrain        float64
+	ShieldRecharge     float64
+	PickupChance       float64
+	PickupLifetime     float64
+	PowerUpTime        float64
+	Weapons            []WeaponDef
+	VectorAsteroids    bool
+	PixelCollision     bool
+	Difficulty         difficulty
+}
+
+func DefaultConfig() Config {
+
+	return Config{
+		MaxShots:           4,
+		Lives:              3,
+		Invulnerability:    3,
+		ExtraLifeEvery:     10000,
+		SaucerInterval:     25,
+		HyperspaceCooldown: 3,
+		HyperspaceRisk:     0.1,
+		Defence:            HyperspaceDefence,
+		ShieldDrain:        0.5,
+		ShieldRecharge:     0.1,
+		PickupChance:       0.15,
+		PickupLifetime:     8,
+		PowerUpTime:        10,
+		Weapons:            defaultWeapons,
+		Difficulty:         Normal,
+	}
+
+}
@@ -0,0 +1,3 @@
+module goasteroids/sim
+
+go 1.20
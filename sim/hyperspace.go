@@ -0,0 +1,40 @@
+package sim
+
+// The ship spends hyperspaceTime out of play on each jump.
+const hyperspaceTime = 0.5
+
+func (w *World) jump(input InputState) {
+
+	ship := &w.Entities[0]
+
+	if !input.Defend || w.Config.Defence != HyperspaceDefence || ship.Hidden || w.hyperspaceCooldown > 0 {
+		return
+	}
+
+	w.emit(HyperspaceOut, ship.X, ship.Y)
+
+	ship.Hidden = true
+	w.hyperspace = hyperspaceTime
+	w.hyperspaceCooldown = w.Config.HyperspaceCooldown
+
+}
+
+// leaveHyperspace drops the ship back in somewhere random, where it may not survive
+// the landing.
+func (w *World) leaveHyperspace() {
+
+	ship := &w.Entities[0]
+
+	ship.X = w.rng.Float64() * ScreenWidth
+	ship.Y = w.rng.Float64() * ScreenHeight
+	ship.Px, ship.Py = ship.X, ship.Y
+	ship.Dx, ship.Dy = 0, 0
+	ship.Hidden = false
+
+	w.emit(HyperspaceIn, ship.X, ship.Y)
+
+	if w.rng.Float64() < w.Config.HyperspaceRisk {
+		w.destroyShip()
+	}
+
+}
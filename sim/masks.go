@@ -0,0 +1,151 @@
+package sim
+
+import (
+	"image"
+	"math"
+)
+
+// AlphaMask records which pixels of a sprite are solid enough to collide with.
+type AlphaMask struct {
+	width  int
+	height int
+	solid  []bool
+	reach  float64
+}
+
+// Masks holds the alpha mask of each sprite, by the kind of entity drawn with it.
+// The renderer fills it in as it loads its images; with none loaded, entities fall
+// back to colliding as circles.
+var Masks = map[EntityType]*AlphaMask{}
+
+const maskAlpha = 0x80
+
+func NewAlphaMask(img image.Image) *AlphaMask {
+
+	b := img.Bounds()
+	m := &AlphaMask{
+		width:  b.Dx(),
+		height: b.Dy(),
+		solid:  make([]bool, b.Dx()*b.Dy()),
+	}
+
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			if a>>8 < maskAlpha {
+				continue
+			}
+			m.solid[y*m.width+x] = true
+			cx := float64(x) + 0.5 - float64(m.width)/2
+			cy := float64(y) + 0.5 - float64(m.height)/2
+			m.reach = math.Max(m.reach, math.Hypot(cx, cy))
+		}
+	}
+
+	return m
+
+}
+
+// reach is how far from its centre any part of the entity can touch something.
+func (e Entity) reach() float64 {
+
+	if e.mask == nil {
+		return e.Radius
+	}
+	return math.Max(e.Radius, e.mask.reach*e.Scale)
+
+}
+
+// solid returns a test for whether a point, given relative to the entity's
+// centre, is solid. The sprite is drawn rotated by angle and then scaled, with its
+// picture's y axis pointing up, so the point is undone in that order before
+// looking it up in the mask.
+func (e Entity) solid() func(x, y float64) bool {
+
+	m := e.mask
+	if m == nil {
+		r := e.Radius * e.Radius
+		return func(x, y float64) bool { return x*x+y*y <= r }
+	}
+
+	s, c := math.Sin(-e.Angle), math.Cos(-e.Angle)
+	w, h := float64(m.width)/2, float64(m.height)/2
+
+	return func(x, y float64) bool {
+		u := int(math.Floor((x*c-y*s)/e.Scale + w))
+		v := int(math.Floor(h - (x*s+y*c)/e.Scale))
+		if u < 0 || v < 0 || u >= m.width || v >= m.height {
+			return false
+		}
+		return m.solid[v*m.width+u]
+	}
+
+}
+
+// maskContact tests two entities for overlap a world pixel at a time, over the
+// box around the smaller of them. The normal runs between their centres, and the
+// depth is how wide the overlap is along it.
+func maskContact(a, b Entity) (nx, ny, depth float64, ok bool) {
+
+	bx, by := b.offset(a)
+
+	nx, ny = 1, 0
+	if d := math.Hypot(bx, by); d > 0 {
+		nx, ny = bx/d, by/d
+	}
+
+	// sample around whichever one is smaller, relative to a's centre
+	cx, cy, r := 0.0, 0.0, a.reach()
+	if b.reach() < r {
+		cx, cy, r = bx, by, b.reach()
+	}
+
+	inA, inB := a.solid(), b.solid()
+	lo, hi := math.Inf(1), math.Inf(-1)
+
+	for y := math.Floor(cy-r) + 0.5; y < cy+r; y++ {
+		for x := math.Floor(cx-r) + 0.5; x < cx+r; x++ {
+			if !inA(x, y) || !inB(x-bx, y-by) {
+				continue
+			}
+			d := x*nx + y*ny
+			lo, hi = math.Min(lo, d), math.Max(hi, d)
+			ok = true
+		}
+	}
+
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	return nx, ny, hi - lo + 1, true
+
+}
+
+// sweepMask refines a swept shot against a target when either has a mask, the
+// same way sweepShape does for outlines.
+func sweepMask(p, a Entity, from, dt float64) (float64, bool) {
+
+	vx, vy := p.Dx-a.Dx, p.Dy-a.Dy
+
+	step := dt
+	if v := math.Hypot(vx, vy); v > 0 {
+		step = math.Max(math.Min(p.Radius, p.reach()), 1) / v
+	}
+
+	for t := from; t <= dt+step; t += step {
+		t = math.Min(t, dt)
+		q := p
+		q.X += vx * t
+		q.Y += vy * t
+		if _, _, _, hit := maskContact(q, a); hit {
+			return t, true
+		}
+		if t == dt {
+			break
+		}
+	}
+
+	return 0, false
+
+}
@@ -0,0 +1,45 @@
+package sim
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// dot is a sprite that is transparent apart from a small solid square in the middle.
+func dot(size, solid int) *AlphaMask {
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	lo := (size - solid) / 2
+	for y := lo; y < lo+solid; y++ {
+		for x := lo; x < lo+solid; x++ {
+			img.SetRGBA(x, y, color.RGBA{A: 0xff})
+		}
+	}
+	return NewAlphaMask(img)
+
+}
+
+func TestMasksOnlyTouchWhereSolid(t *testing.T) {
+
+	m := dot(20, 4)
+	a := Entity{Radius: 10, Scale: 1, mask: m}
+
+	cases := []struct {
+		name  string
+		x     float64
+		touch bool
+	}{
+		{"circles overlap, pixels don't", 12, false},
+		{"pixels overlap", 3, true},
+		{"across the wrap", PlayfieldWidth - 3, true},
+	}
+
+	for _, c := range cases {
+		b := Entity{X: c.x, Radius: 10, Scale: 1, mask: m}
+		if _, _, _, ok := maskContact(a, b); ok != c.touch {
+			t.Errorf("%s: touching %v", c.name, ok)
+		}
+	}
+
+}
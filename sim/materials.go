@@ -0,0 +1,154 @@
+package sim
+
+import "math"
+
+type Material int
+
+// Rock is the zero value, so any asteroid not given a material is plain rock.
+const (
+	Rock      Material = 0
+	Iron      Material = 1
+	Ice       Material = 2
+	Explosive Material = 3
+	Magnetic  Material = 4
+)
+
+var materialOrder = []Material{Rock, Iron, Ice, Explosive, Magnetic}
+
+// materialSpec says how an asteroid of a material behaves. It takes hits shots
+// to break, then splits into fragments pieces each shrink times its size. An
+// asteroid with a blast radius explodes instead, hitting everything within
+// blast times its radius, and one with pull drags the ship towards it.
+type materialSpec struct {
+	hits      int
+	fragments int
+	shrink    float64
+	points    float64
+	blast     float64
+	pull      float64
+}
+
+var materials = map[Material]materialSpec{
+	Rock:      {hits: 1, fragments: 2, shrink: 0.75, points: 1},
+	Iron:      {hits: 3, fragments: 2, shrink: 0.75, points: 3},
+	Ice:       {hits: 1, fragments: 4, shrink: 0.6, points: 1.5},
+	Explosive: {hits: 1, fragments: 2, shrink: 0.75, points: 2, blast: 3},
+	Magnetic:  {hits: 1, fragments: 2, shrink: 0.75, points: 2, pull: 4e6},
+}
+
+// Magnetic asteroids only reach the ship within magnetRange, and never pull it
+// harder than maxMagnetPull.
+const magnetRange = 300
+const maxMagnetPull = 400
+
+// materialWeights gives the relative odds of each material spawning in a wave.
+// The first wave is all rock; tougher and stranger asteroids creep in after.
+func materialWeights(wave int) []float64 {
+
+	later := float64(wave - 1)
+
+	return []float64{
+		100,
+		math.Min(30, 6*later),
+		math.Min(30, 6*later),
+		math.Min(20, 4*(later-1)),
+		math.Min(20, 4*(later-2)),
+	}
+
+}
+
+func (w *World) pickMaterial() Material {
+
+	weights := materialWeights(w.Wave)
+
+	total := 0.0
+	for _, weight := range weights {
+		total += math.Max(0, weight)
+	}
+
+	roll := w.rng.Float64() * total
+	for n, weight := range weights {
+		roll -= math.Max(0, weight)
+		if roll < 0 {
+			return materialOrder[n]
+		}
+	}
+	return Rock
+
+}
+
+func (w *World) asteroidPoints(a Entity) int {
+
+	return int(float64(asteroidPoints[asteroidTier(a.Radius)]) * materials[a.Material].points)
+
+}
+
+// explode destroys an explosive asteroid and lands a blast on everything around
+// it, which can set off other explosive asteroids in turn.
+func (w *World) explode(target int, owner int) []Entity {
+
+	es := w.Entities
+	centre := es[target]
+	es[target].Dead = true
+
+	reach := centre.Radius * materials[centre.Material].blast
+
+	w.emit(Explosion, centre.X, centre.Y)
+
+	var spawned []Entity
+
+	for k := range es {
+
+		e := es[k]
+		if e.Dead || e.Hidden || e.EntityType == Projectile || e.EntityType == Pickup {
+			continue
+		}
+
+		ox, oy := e.offset(centre)
+		d := math.Hypot(ox, oy)
+		if d > reach+e.Radius {
+			continue
+		}
+
+		blast := Entity{EntityType: Projectile, Owner: owner}
+		if d > 0 {
+			blast.Dx, blast.Dy = ox/d*300, oy/d*300
+		}
+
+		spawned = append(spawned, w.hit(blast, k)...)
+
+	}
+
+	return spawned
+
+}
+
+// magnetise pulls the ship towards every magnetic asteroid close enough, more
+// strongly the closer it gets.
+func (w *World) magnetise(dt float64) {
+
+	ship := &w.Entities[0]
+	if ship.Hidden {
+		return
+	}
+
+	for _, e := range w.Entities {
+
+		pull := materials[e.Material].pull
+		if e.EntityType != Asteroid || e.Dead || pull == 0 {
+			continue
+		}
+
+		ox, oy := e.offset(*ship)
+		d := math.Hypot(ox, oy)
+		if d > magnetRange || d == 0 {
+			continue
+		}
+
+		a := math.Min(maxMagnetPull, pull/(d*d))
+		ship.Dx += ox / d * a * dt
+		ship.Dy += oy / d * a * dt
+
+	}
+
+}
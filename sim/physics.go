@@ -0,0 +1,124 @@
+package sim
+
+import "math"
+
+const restitution = 1.0
+const friction = 0.2
+
+// Overlap below slop is left alone so resting contacts don't jitter, and only
+// part of the rest is corrected each step so pushes apart stay smooth.
+const penetrationSlop = 0.5
+const penetrationCorrection = 0.8
+
+// Mass goes with area, and every body is treated as a uniform disc.
+func (e Entity) invMass() float64 {
+
+	return 1 / (e.Radius * e.Radius)
+
+}
+
+// The ship's heading belongs to the pilot, so collisions never spin it.
+func (e Entity) invInertia() float64 {
+
+	if e.EntityType == Ship {
+		return 0
+	}
+	return 2 * e.invMass() / (e.Radius * e.Radius)
+
+}
+
+// resolveCollision applies equal and opposite impulses to two overlapping bodies:
+// one along the contact normal (nx, ny) from a to b scaled by restitution, and one
+// across it limited by friction that sets them spinning. It then pushes them
+// apart by the overlap depth so they can't stay stuck inside each other.
+// Momentum is always conserved. Energy is too for head-on contacts, but friction
+// on a glancing one gives a little of it up, so rocks that graze slow slightly.
+func resolveCollision(a, b *Entity, nx, ny, depth float64) {
+
+	invMa, invMb := a.invMass(), b.invMass()
+	invIa, invIb := a.invInertia(), b.invInertia()
+
+	// contact point relative to each centre
+	rax, ray := nx*a.Radius, ny*a.Radius
+	rbx, rby := -nx*b.Radius, -ny*b.Radius
+
+	rvx := (b.Dx - b.spin*rby) - (a.Dx - a.spin*ray)
+	rvy := (b.Dy + b.spin*rbx) - (a.Dy + a.spin*rax)
+
+	vn := rvx*nx + rvy*ny
+
+	if vn < 0 {
+
+		jn := -(1 + restitution) * vn / (invMa + invMb)
+
+		a.Dx -= jn * nx * invMa
+		a.Dy -= jn * ny * invMa
+		b.Dx += jn * nx * invMb
+		b.Dy += jn * ny * invMb
+
+		tx, ty := rvx-vn*nx, rvy-vn*ny
+		if t := math.Hypot(tx, ty); t > 1e-9 {
+
+			tx /= t
+			ty /= t
+
+			raCrossT := rax*ty - ray*tx
+			rbCrossT := rbx*ty - rby*tx
+
+			jt := -(rvx*tx + rvy*ty) /
+				(invMa + invMb + raCrossT*raCrossT*invIa + rbCrossT*rbCrossT*invIb)
+			jt = math.Max(-friction*jn, math.Min(friction*jn, jt))
+
+			a.Dx -= jt * tx * invMa
+			a.Dy -= jt * ty * invMa
+			a.spin -= raCrossT * jt * invIa
+			b.Dx += jt * tx * invMb
+			b.Dy += jt * ty * invMb
+			b.spin += rbCrossT * jt * invIb
+
+		}
+
+	}
+
+	if overlap := depth; overlap > penetrationSlop {
+
+		c := (overlap - penetrationSlop) * penetrationCorrection / (invMa + invMb)
+
+		a.X -= c * nx * invMa
+		a.Y -= c * ny * invMa
+		b.X += c * nx * invMb
+		b.Y += c * ny * invMb
+
+	}
+
+}
+
+// timeOfImpact finds when, within the next dt seconds, two circles moving at
+// constant velocity first touch. Circles already touching hit at time zero.
+func (e Entity) timeOfImpact(e2 Entity, dt float64) (float64, bool) {
+
+	px, py := e.offset(e2)
+	vx, vy := e.Dx-e2.Dx, e.Dy-e2.Dy
+	r := e.reach() + e2.reach()
+
+	c := px*px + py*py - r*r
+	if c <= 0 {
+		return 0, true
+	}
+
+	a := vx*vx + vy*vy
+	b := px*vx + py*vy
+	if a == 0 || b >= 0 {
+		return 0, false
+	}
+
+	disc := b*b - a*c
+	if disc < 0 {
+		return 0, false
+	}
+
+	t := (-b - math.Sqrt(disc)) / a
+
+	return t, t <= dt
+
+}
@@ -0,0 +1,96 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func momentum(a, b Entity) (float64, float64) {
+
+	return a.Dx/a.invMass() + b.Dx/b.invMass(), a.Dy/a.invMass() + b.Dy/b.invMass()
+
+}
+
+// kineticEnergy counts spin as well as travel. The ship never spins from a hit,
+// so it has no rotational energy to count.
+func kineticEnergy(a, b Entity) float64 {
+
+	total := 0.0
+	for _, e := range []Entity{a, b} {
+		total += 0.5 * (e.Dx*e.Dx + e.Dy*e.Dy) / e.invMass()
+		if e.invInertia() > 0 {
+			total += 0.5 * e.spin * e.spin / e.invInertia()
+		}
+	}
+	return total
+
+}
+
+func collideOnce(a, b Entity) (Entity, Entity) {
+
+	nx, ny, depth, ok := contact(a, b)
+	if !ok {
+		panic("bodies aren't touching")
+	}
+	resolveCollision(&a, &b, nx, ny, depth)
+	return a, b
+
+}
+
+func near(x, y float64) bool {
+
+	return math.Abs(x-y) <= 1e-9*math.Max(1, math.Max(math.Abs(x), math.Abs(y)))
+
+}
+
+func TestHeadOnCollisionConservesMomentumAndEnergy(t *testing.T) {
+
+	a := Entity{EntityType: Asteroid, X: 0, Y: 0, Dx: 100, Radius: 45}
+	b := Entity{EntityType: Asteroid, X: 60, Y: 0, Dx: -30, Radius: 20}
+
+	px, py := momentum(a, b)
+	e := kineticEnergy(a, b)
+
+	a, b = collideOnce(a, b)
+
+	if qx, qy := momentum(a, b); !near(px, qx) || !near(py, qy) {
+		t.Errorf("momentum %v,%v became %v,%v", px, py, qx, qy)
+	}
+	if e2 := kineticEnergy(a, b); !near(e, e2) {
+		t.Errorf("energy %v became %v", e, e2)
+	}
+	if a.spin != 0 || b.spin != 0 {
+		t.Errorf("head-on hit set them spinning: %v, %v", a.spin, b.spin)
+	}
+
+}
+
+// Friction takes a little energy out of glancing contacts, so those only have to
+// conserve momentum and never gain energy.
+func TestGlancingCollisionConservesMomentum(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a, b Entity
+	}{
+		{"asteroids", Entity{EntityType: Asteroid, Dx: 100, Dy: 20, Radius: 45, spin: 1}, Entity{EntityType: Asteroid, X: 50, Y: 40, Dx: -30, Radius: 20}},
+		{"ship", Entity{EntityType: Ship, Dx: 100, Dy: 20, Radius: 30}, Entity{EntityType: Asteroid, X: 50, Y: 40, Dx: -30, Radius: 45, spin: 2}},
+	}
+
+	for _, c := range cases {
+
+		px, py := momentum(c.a, c.b)
+		e := kineticEnergy(c.a, c.b)
+
+		a, b := collideOnce(c.a, c.b)
+
+		if qx, qy := momentum(a, b); !near(px, qx) || !near(py, qy) {
+			t.Errorf("%s: momentum %v,%v became %v,%v", c.name, px, py, qx, qy)
+		}
+		if e2 := kineticEnergy(a, b); e2 > e*(1+1e-9) {
+			t.Errorf("%s: energy rose from %v to %v", c.name, e, e2)
+		}
+
+	}
+
+}
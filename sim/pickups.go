@@ -0,0 +1,90 @@
+package sim
+
+type PickupKind int
+
+const (
+	SpreadShot    PickupKind = 1
+	RapidFire     PickupKind = 2
+	PiercingShots PickupKind = 3
+	ShieldRefill  PickupKind = 4
+	ExtraLife     PickupKind = 5
+)
+
+var PickupKinds = []PickupKind{SpreadShot, RapidFire, PiercingShots, ShieldRefill, ExtraLife}
+
+const PickupRadius = 16
+
+const spreadAngle = 0.15
+
+// A piercing shot passes through whatever it hits, so it ignores targets for
+// long enough to clear the rock it has just gone through.
+const pierceTime = 0.1
+
+// dropPickup gives a destroyed small asteroid its chance of leaving a pickup
+// behind, drifting on at half the asteroid's speed.
+func (w *World) dropPickup(a Entity) []Entity {
+
+	if w.rng.Float64() >= w.Config.PickupChance {
+		return nil
+	}
+
+	kinds := w.droppable()
+
+	return []Entity{{
+		EntityType: Pickup,
+		Pickup:     kinds[w.rng.Intn(len(kinds))],
+		X:          a.X,
+		Y:          a.Y,
+		Dx:         a.Dx / 2,
+		Dy:         a.Dy / 2,
+		Radius:     PickupRadius,
+		Scale:      1,
+		TTL:        w.Config.PickupLifetime,
+	}}
+
+}
+
+// droppable lists the pickups worth dropping in this game, since a shield refill
+// is no use to a pilot flying with hyperspace.
+func (w *World) droppable() []PickupKind {
+
+	var kinds []PickupKind
+	for _, kind := range PickupKinds {
+		if kind != ShieldRefill || w.Config.Defence == ShieldDefence {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+
+}
+
+func (w *World) collect(p *Entity) {
+
+	p.Dead = true
+
+	switch p.Pickup {
+	case ShieldRefill:
+		w.ShieldEnergy = 1
+	case ExtraLife:
+		w.Lives++
+	default:
+		w.PowerUps[p.Pickup] = w.Config.PowerUpTime
+	}
+
+}
+
+func (w *World) poweredUp(kind PickupKind) bool {
+
+	return w.PowerUps[kind] > 0
+
+}
+
+func (w *World) updatePowerUps(dt float64) {
+
+	for kind, left := range w.PowerUps {
+		if left > 0 {
+			w.PowerUps[kind] = left - dt
+		}
+	}
+
+}
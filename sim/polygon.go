@@ -0,0 +1,431 @@
+package sim
+
+import "math"
+
+// Point is a vertex of an asteroid's outline, relative to its centre and before
+// it is rotated by the asteroid's angle.
+type Point struct {
+	X float64
+	Y float64
+}
+
+func (p Point) Rotated(angle float64) Point {
+
+	s, c := math.Sin(angle), math.Cos(angle)
+	return Point{p.X*c - p.Y*s, p.X*s + p.Y*c}
+
+}
+
+// rockShape makes a jagged outline that stays within radius. Every vertex is at
+// its own angle around the centre, so the outline is star-shaped about it and
+// splits cleanly into a fan of triangles.
+func (w *World) rockShape(radius float64) []Point {
+
+	n := 9 + w.rng.Intn(5)
+	shape := make([]Point, n)
+
+	for k := range shape {
+		a := (float64(k) + w.rng.Float64()*0.6 - 0.3) * 2 * math.Pi / float64(n)
+		r := radius * (0.65 + 0.35*w.rng.Float64())
+		shape[k] = Point{r * math.Cos(a), r * math.Sin(a)}
+	}
+
+	return shape
+
+}
+
+// outline places the entity's shape in the world, centred at (cx, cy).
+func (e Entity) outline(cx, cy float64) []Point {
+
+	out := make([]Point, len(e.Shape))
+	for k, p := range e.Shape {
+		r := p.Rotated(e.Angle)
+		out[k] = Point{cx + r.X, cy + r.Y}
+	}
+	return out
+
+}
+
+// fan cuts a star-shaped outline around (cx, cy) into convex triangles.
+func fan(outline []Point, cx, cy float64) [][3]Point {
+
+	tris := make([][3]Point, len(outline))
+	for k := range outline {
+		tris[k] = [3]Point{{cx, cy}, outline[k], outline[(k+1)%len(outline)]}
+	}
+	return tris
+
+}
+
+// contact tests whether two entities overlap, using their outlines where they
+// have them and circles where they don't. The normal points from a to b, and
+// depth is how far they would have to move apart along it to separate.
+func contact(a, b Entity) (nx, ny, depth float64, ok bool) {
+
+	if !a.collidesWith(b) {
+		return 0, 0, 0, false
+	}
+
+	bx, by := b.offset(a)
+
+	switch {
+	case len(a.Shape) == 0 && len(b.Shape) == 0 && (a.mask != nil || b.mask != nil):
+		return maskContact(a, b)
+
+	case len(a.Shape) == 0 && len(b.Shape) == 0:
+		d := math.Hypot(bx, by)
+		nx, ny = 1, 0
+		if d > 0 {
+			nx, ny = bx/d, by/d
+		}
+		return nx, ny, a.Radius + b.Radius - d, true
+
+	case len(b.Shape) == 0:
+		return polygonCircle(fan(a.outline(0, 0), 0, 0), Point{bx, by}, b.Radius)
+
+	case len(a.Shape) == 0:
+		nx, ny, depth, ok = polygonCircle(fan(b.outline(bx, by), bx, by), Point{0, 0}, a.Radius)
+		return -nx, -ny, depth, ok
+	}
+
+	tas := fan(a.outline(0, 0), 0, 0)
+	tbs := fan(b.outline(bx, by), bx, by)
+
+	for _, ta := range tas {
+		for _, tb := range tbs {
+			if tx, ty, td, hit := triangleTriangle(ta, tb); hit && td > depth {
+				nx, ny, depth, ok = tx, ty, td, true
+			}
+		}
+	}
+
+	return nx, ny, depth, ok
+
+}
+
+// polygonCircle tests a circle against every triangle of a polygon and keeps the
+// deepest overlap, with the normal pointing out of the polygon.
+func polygonCircle(tris [][3]Point, c Point, r float64) (nx, ny, depth float64, ok bool) {
+
+	for _, t := range tris {
+		if tx, ty, td, hit := triangleCircle(t, c, r); hit && td > depth {
+			nx, ny, depth, ok = tx, ty, td, true
+		}
+	}
+	return nx, ny, depth, ok
+
+}
+
+func closestOnSegment(a, b, p Point) Point {
+
+	ex, ey := b.X-a.X, b.Y-a.Y
+	l := ex*ex + ey*ey
+	if l == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, ((p.X-a.X)*ex+(p.Y-a.Y)*ey)/l))
+	return Point{a.X + ex*t, a.Y + ey*t}
+
+}
+
+func cross(o, a, b Point) float64 {
+
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+
+}
+
+func triangleCircle(t [3]Point, c Point, r float64) (nx, ny, depth float64, ok bool) {
+
+	d1, d2, d3 := cross(t[0], t[1], c), cross(t[1], t[2], c), cross(t[2], t[0], c)
+	inside := (d1 >= 0 && d2 >= 0 && d3 >= 0) || (d1 <= 0 && d2 <= 0 && d3 <= 0)
+
+	best := math.Inf(1)
+	var q Point
+	for k := 0; k < 3; k++ {
+		p := closestOnSegment(t[k], t[(k+1)%3], c)
+		if d := math.Hypot(c.X-p.X, c.Y-p.Y); d < best {
+			best, q = d, p
+		}
+	}
+
+	if inside {
+		// push out through the nearest edge, away from the triangle's middle
+		mx := (t[0].X + t[1].X + t[2].X) / 3
+		my := (t[0].Y + t[1].Y + t[2].Y) / 3
+		nx, ny = q.X-mx, q.Y-my
+		if l := math.Hypot(nx, ny); l > 0 {
+			nx, ny = nx/l, ny/l
+		}
+		return nx, ny, r + best, true
+	}
+
+	if best > r {
+		return 0, 0, 0, false
+	}
+
+	nx, ny = 1, 0
+	if best > 0 {
+		nx, ny = (c.X-q.X)/best, (c.Y-q.Y)/best
+	}
+	return nx, ny, r - best, true
+
+}
+
+// triangleTriangle is the separating axis test: two convex shapes overlap unless
+// some edge normal of either one has a gap between their projections onto it.
+func triangleTriangle(a, b [3]Point) (nx, ny, depth float64, ok bool) {
+
+	depth = math.Inf(1)
+
+	for _, t := range [][3]Point{a, b} {
+		for k := 0; k < 3; k++ {
+
+			p, q := t[k], t[(k+1)%3]
+			ax, ay := q.Y-p.Y, p.X-q.X
+			l := math.Hypot(ax, ay)
+			if l == 0 {
+				continue
+			}
+			ax, ay = ax/l, ay/l
+
+			aMin, aMax := project(a, ax, ay)
+			bMin, bMax := project(b, ax, ay)
+
+			overlap := math.Min(aMax, bMax) - math.Max(aMin, bMin)
+			if overlap <= 0 {
+				return 0, 0, 0, false
+			}
+			if overlap < depth {
+				depth, nx, ny = overlap, ax, ay
+				if (bMin+bMax)/2 < (aMin+aMax)/2 {
+					nx, ny = -nx, -ny
+				}
+			}
+
+		}
+	}
+
+	return nx, ny, depth, true
+
+}
+
+func project(t [3]Point, ax, ay float64) (float64, float64) {
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range t {
+		d := p.X*ax + p.Y*ay
+		lo, hi = math.Min(lo, d), math.Max(hi, d)
+	}
+	return lo, hi
+
+}
+
+// sweepShape refines a swept shot against an asteroid with an outline. The
+// circles first touch at time from, so step the shot on from there a little
+// under its own radius at a time until it meets the outline itself.
+func sweepShape(p, a Entity, from, dt float64) (float64, bool) {
+
+	px, py := p.offset(a)
+	vx, vy := p.Dx-a.Dx, p.Dy-a.Dy
+
+	step := dt
+	if v := math.Hypot(vx, vy); v > 0 {
+		step = math.Max(p.Radius, 1) / v
+	}
+
+	tris := fan(a.outline(0, 0), 0, 0)
+
+	for t := from; t <= dt+step; t += step {
+		t = math.Min(t, dt)
+		if _, _, _, hit := polygonCircle(tris, Point{px + vx*t, py + vy*t}, p.Radius); hit {
+			return t, true
+		}
+		if t == dt {
+			break
+		}
+	}
+
+	return 0, false
+
+}
+
+// rayPolygon finds how far along a ray from the origin it first crosses an
+// outline, if it does at all.
+func rayPolygon(outline []Point, dirX, dirY float64) (float64, bool) {
+
+	best := math.Inf(1)
+
+	for k := range outline {
+
+		a, b := outline[k], outline[(k+1)%len(outline)]
+		ex, ey := b.X-a.X, b.Y-a.Y
+
+		denom := dirX*ey - dirY*ex
+		if denom == 0 {
+			continue
+		}
+
+		t := (a.X*ey - a.Y*ex) / denom
+		s := (a.X*dirY - a.Y*dirX) / denom
+		if t >= 0 && s >= 0 && s <= 1 && t < best {
+			best = t
+		}
+
+	}
+
+	return best, !math.IsInf(best, 1)
+
+}
+
+// clipHalfPlane keeps the part of a polygon where p·n >= 0.
+func clipHalfPlane(poly []Point, nx, ny float64) []Point {
+
+	var out []Point
+
+	for k := range poly {
+
+		a, b := poly[k], poly[(k+1)%len(poly)]
+		da, db := a.X*nx+a.Y*ny, b.X*nx+b.Y*ny
+
+		if da >= 0 {
+			out = append(out, a)
+		}
+		if (da >= 0) != (db >= 0) {
+			t := da / (da - db)
+			out = append(out, Point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t})
+		}
+
+	}
+
+	return out
+
+}
+
+func centroid(poly []Point) (Point, float64) {
+
+	var cx, cy, area float64
+
+	for k := range poly {
+		a, b := poly[k], poly[(k+1)%len(poly)]
+		c := a.X*b.Y - b.X*a.Y
+		area += c
+		cx += (a.X + b.X) * c
+		cy += (a.Y + b.Y) * c
+	}
+
+	area /= 2
+	if math.Abs(area) < 1e-9 {
+		return Point{}, 0
+	}
+
+	return Point{cx / (6 * area), cy / (6 * area)}, math.Abs(area)
+
+}
+
+// starify makes sure an outline is star-shaped about its origin. One that isn't
+// already is resampled as seen from the origin, keeping the nearest edge in each
+// direction. Outlines the origin falls outside of are returned as they are.
+func starify(poly []Point) []Point {
+
+	n := len(poly)
+	if n < 3 {
+		return poly
+	}
+
+	star := true
+	for k := range poly {
+		if cross(Point{}, poly[k], poly[(k+1)%n]) <= 0 {
+			star = false
+			break
+		}
+	}
+	if star {
+		return poly
+	}
+
+	n *= 2
+
+	out := make([]Point, 0, n)
+
+	for k := 0; k < n; k++ {
+		a := 2 * math.Pi * float64(k) / float64(n)
+		dx, dy := math.Cos(a), math.Sin(a)
+		t, ok := rayPolygon(poly, dx, dy)
+		if !ok {
+			return poly
+		}
+		out = append(out, Point{dx * t, dy * t})
+	}
+
+	return out
+
+}
+
+// cutAsteroid splits an asteroid with an outline into wedges meeting at its
+// centre, the first cut running along the shot's path. Each wedge becomes a
+// fragment centred on its own middle and flying away from where it was cut.
+// Fragments are scaled to shrink times the parent's radius, the same as sprite
+// rocks, so they score and break down the same way whatever shape they were cut.
+func cutAsteroid(a *Entity, dx, dy, v float64, pieces int, shrink float64) []Entity {
+
+	base := math.Atan2(dy, dx) - a.Angle
+	parent := *a
+
+	var fragments []Entity
+
+	for k := 0; k < pieces; k++ {
+
+		from := base + 2*math.Pi*float64(k)/float64(pieces)
+		to := base + 2*math.Pi*float64(k+1)/float64(pieces)
+
+		wedge := clipHalfPlane(parent.Shape, -math.Sin(from), math.Cos(from))
+		wedge = clipHalfPlane(wedge, math.Sin(to), -math.Cos(to))
+
+		c, area := centroid(wedge)
+		if area < 1 {
+			continue
+		}
+
+		local := make([]Point, len(wedge))
+		radius := 0.0
+		for n, p := range wedge {
+			local[n] = Point{p.X - c.X, p.Y - c.Y}
+		}
+		local = starify(local)
+		for _, p := range local {
+			radius = math.Max(radius, math.Hypot(p.X, p.Y))
+		}
+		size := parent.Radius * shrink
+		for n := range local {
+			local[n].X *= size / radius
+			local[n].Y *= size / radius
+		}
+
+		shift := c.Rotated(parent.Angle)
+		out := math.Hypot(shift.X, shift.Y)
+
+		f := parent
+		f.Shape = local
+		f.Radius = size
+		f.Scale = parent.Scale * shrink
+		f.X += shift.X
+		f.Y += shift.Y
+		if out > 0 {
+			f.Dx = shift.X / out * v * 2
+			f.Dy = shift.Y / out * v * 2
+		}
+
+		fragments = append(fragments, f)
+
+	}
+
+	if len(fragments) == 0 {
+		a.Dead = true
+		return nil
+	}
+
+	*a = fragments[0]
+
+	return fragments[1:]
+
+}
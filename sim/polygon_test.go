@@ -0,0 +1,59 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+// splitsToDestroy keeps shooting the first piece of a rock from the same side
+// until nothing is left of it, and counts the shots.
+func splitsToDestroy(a Entity, shot Entity) int {
+
+	for n := 1; ; n++ {
+		splitAsteroid(shot, &a)
+		if a.Dead {
+			return n
+		}
+	}
+
+}
+
+func TestVectorRocksSplitLikeSpriteRocks(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	spec := materials[Rock]
+
+	sprite := Entity{EntityType: Asteroid, Radius: 45, Scale: 0.1, Material: Rock, hp: spec.hits}
+	vector := sprite
+	vector.Shape = w.rockShape(sprite.Radius)
+
+	for k := 0; k < 8; k++ {
+
+		angle := 2 * math.Pi * float64(k) / 8
+		shot := Entity{EntityType: Projectile, Dx: 500 * math.Cos(angle), Dy: 500 * math.Sin(angle)}
+
+		a := vector
+		a.Shape = append([]Point(nil), vector.Shape...)
+		fragments := append(splitAsteroid(shot, &a), a)
+
+		for _, f := range fragments {
+			if want := sprite.Radius * spec.shrink; math.Abs(f.Radius-want) > 1e-9 {
+				t.Fatalf("shot at %.2f: fragment radius %v, want %v", angle, f.Radius, want)
+			}
+			if asteroidTier(f.Radius) != asteroidTier(sprite.Radius*spec.shrink) {
+				t.Fatalf("shot at %.2f: fragment scores as tier %v", angle, asteroidTier(f.Radius))
+			}
+			for _, p := range f.Shape {
+				if math.Hypot(p.X, p.Y) > f.Radius+1e-9 {
+					t.Fatalf("shot at %.2f: outline reaches past the fragment's radius", angle)
+				}
+			}
+		}
+
+		if got, want := splitsToDestroy(vector, shot), splitsToDestroy(sprite, shot); got != want {
+			t.Fatalf("shot at %.2f: vector rock took %d shots, sprite rock %d", angle, got, want)
+		}
+
+	}
+
+}
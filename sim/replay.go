@@ -0,0 +1,105 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReplayVersion must go up whenever the recorded config, the input bits or the
+// rules of the simulation change, since an old replay would play out differently.
+const ReplayVersion = 5
+
+// Replay is everything needed to play a game back exactly: the seed and config
+// the world was built from and the input held on every tick, packed into bit flags.
+type Replay struct {
+	Version int      `json:"version"`
+	Seed    int64    `json:"seed"`
+	Config  Config   `json:"config"`
+	Inputs  []uint16 `json:"inputs"`
+}
+
+func (input InputState) bits() uint16 {
+
+	var b uint16
+	for i, held := range input.flags() {
+		if *held {
+			b |= 1 << uint(i)
+		}
+	}
+	return b
+
+}
+
+func inputFromBits(b uint16) InputState {
+
+	var input InputState
+	for i, held := range input.flags() {
+		*held = b&(1<<uint(i)) != 0
+	}
+	return input
+
+}
+
+// flags lists the controls in the order they are packed into a replay, so new
+// controls must only ever be added to the end.
+func (input *InputState) flags() []*bool {
+
+	return []*bool{
+		&input.RotateLeft,
+		&input.RotateRight,
+		&input.Thrust,
+		&input.Reverse,
+		&input.StrafeLeft,
+		&input.StrafeRight,
+		&input.Fire,
+		&input.Defend,
+		&input.NextWeapon,
+		&input.PrevWeapon,
+	}
+
+}
+
+func (r *Replay) Record(input InputState) {
+
+	r.Inputs = append(r.Inputs, input.bits())
+
+}
+
+func (r *Replay) Input(tick int) (InputState, bool) {
+
+	if r == nil || tick >= len(r.Inputs) {
+		return InputState{}, false
+	}
+	return inputFromBits(r.Inputs[tick]), true
+
+}
+
+func LoadReplay(path string) (*Replay, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("%s: unsupported replay version %d", path, r.Version)
+	}
+
+	return &r, nil
+
+}
+
+func (r *Replay) Save(path string) error {
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+
+}
@@ -0,0 +1,147 @@
+package sim
+
+import "math"
+
+type saucerKind int
+
+const (
+	LargeSaucer saucerKind = 1
+	SmallSaucer saucerKind = 2
+)
+
+type saucerSpec struct {
+	radius   float64
+	speed    float64
+	reload   float64
+	points   int
+	aimed    bool
+	accuracy float64
+}
+
+// Large saucers are slow and spray shots anywhere; small ones are quick and aim
+// at the ship, missing by up to accuracy radians either way.
+var saucerSpecs = map[saucerKind]saucerSpec{
+	LargeSaucer: {radius: 30, speed: 100, reload: 1.2, points: 200},
+	SmallSaucer: {radius: 18, speed: 150, reload: 1.0, points: 1000, aimed: true, accuracy: 0.25},
+}
+
+const saucerShotSpeed = 300
+const saucerShotLifetime = 1.5
+
+// A saucer changes course every saucerTurnTime seconds as it crosses the screen.
+const saucerTurnTime = 1.5
+
+// updateSaucers counts down to the next saucer while none is in play, and
+// steers and fires any that are.
+func (w *World) updateSaucers(dt float64) {
+
+	if w.GameOver || w.Config.SaucerInterval <= 0 {
+		return
+	}
+
+	present := false
+
+	for i := range w.Entities {
+
+		if w.Entities[i].EntityType != Saucer || w.Entities[i].Dead {
+			continue
+		}
+
+		present = true
+		s := &w.Entities[i]
+		spec := saucerSpecs[s.saucer]
+
+		s.turn -= dt
+		if s.turn <= 0 {
+			s.turn = saucerTurnTime
+			s.Dy = float64(w.rng.Intn(3)-1) * spec.speed / 2
+		}
+
+		s.reload -= dt
+		if s.reload <= 0 {
+			s.reload = spec.reload
+			w.saucerFire(i)
+		}
+
+	}
+
+	if present {
+		return
+	}
+
+	w.saucerTimer -= dt
+	if w.saucerTimer <= 0 {
+		w.saucerTimer = w.Config.SaucerInterval * w.Config.Difficulty.spec().saucers * (0.75 + w.rng.Float64()/2)
+		w.spawnSaucer()
+	}
+
+}
+
+// spawnSaucer sends a saucer in from the left or right edge. Small saucers turn
+// up more often as the waves go on.
+func (w *World) spawnSaucer() {
+
+	kind := LargeSaucer
+	if w.rng.Float64() < math.Min(0.8, 0.15*float64(w.Wave)) {
+		kind = SmallSaucer
+	}
+	spec := saucerSpecs[kind]
+
+	x, dx := -50.0, spec.speed
+	if w.rng.Intn(2) == 0 {
+		x, dx = ScreenWidth+50, -spec.speed
+	}
+
+	w.Spawn(Entity{
+		EntityType: Saucer,
+		saucer:     kind,
+		X:          x,
+		Y:          w.rng.Float64() * ScreenHeight,
+		Dx:         dx,
+		Radius:     spec.radius,
+		Scale:      1,
+		TTL:        PlayfieldWidth / spec.speed,
+		turn:       saucerTurnTime,
+		reload:     spec.reload,
+	})
+
+}
+
+func (w *World) saucerFire(i int) {
+
+	s := w.Entities[i]
+	spec := saucerSpecs[s.saucer]
+
+	angle := w.rng.Float64() * 2 * math.Pi
+	if ship := w.Entities[0]; spec.aimed && !ship.Hidden {
+		dx, dy := ship.offset(s)
+		angle = math.Atan2(dy, dx) + (w.rng.Float64()*2-1)*spec.accuracy
+	}
+
+	dx, dy := math.Cos(angle), math.Sin(angle)
+
+	w.Spawn(Entity{
+		EntityType: Projectile,
+		Owner:      s.ID,
+		X:          s.X + (s.Radius+10)*dx,
+		Y:          s.Y + (s.Radius+10)*dy,
+		Dx:         saucerShotSpeed * dx,
+		Dy:         saucerShotSpeed * dy,
+		Angle:      angle - math.Pi/2,
+		Radius:     10,
+		Scale:      0.05,
+		TTL:        saucerShotLifetime,
+	})
+
+}
+
+// destroySaucer removes a saucer, paying out for it if the player was responsible.
+func (w *World) destroySaucer(s *Entity, byPlayer bool) {
+
+	s.Dead = true
+
+	if byPlayer {
+		w.award(saucerSpecs[s.saucer].points)
+	}
+
+}
@@ -0,0 +1,46 @@
+package sim
+
+type sizeTier int
+
+const (
+	Small  sizeTier = 1
+	Medium sizeTier = 2
+	Large  sizeTier = 3
+)
+
+// Asteroids start at radius 45 and shrink by a quarter each split, so anything
+// still at least 40 is fresh, and below 20 it is too small to split again.
+func asteroidTier(radius float64) sizeTier {
+
+	switch {
+	case radius >= 40:
+		return Large
+	case radius >= 20:
+		return Medium
+	default:
+		return Small
+	}
+
+}
+
+var asteroidPoints = map[sizeTier]int{
+	Large:  20,
+	Medium: 50,
+	Small:  100,
+}
+
+// award adds points to the score, with an extra life for every milestone passed.
+func (w *World) award(points int) {
+
+	w.Score += points
+
+	if w.Config.ExtraLifeEvery <= 0 {
+		return
+	}
+
+	for w.Score >= w.nextExtraLife {
+		w.Lives++
+		w.nextExtraLife += w.Config.ExtraLifeEvery
+	}
+
+}
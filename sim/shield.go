@@ -0,0 +1,41 @@
+package sim
+
+// Once the shield runs dry it stays down until it has recharged to shieldRestart,
+// or holding the key would flick it up on every sliver of recharge.
+const shieldRestart = 0.25
+
+// raiseShield holds the shield up for as long as the pilot asks and there is
+// energy left, draining it while up and recharging it while down.
+func (w *World) raiseShield(dt float64, input InputState) {
+
+	if w.ShieldEnergy >= shieldRestart {
+		w.shieldDrained = false
+	}
+
+	w.ShieldUp = input.Defend &&
+		w.Config.Defence == ShieldDefence &&
+		!w.Entities[0].Hidden &&
+		!w.shieldDrained
+
+	if w.ShieldUp {
+		w.ShieldEnergy -= w.Config.ShieldDrain * dt
+	} else {
+		w.ShieldEnergy += w.Config.ShieldRecharge * dt
+	}
+
+	if w.ShieldEnergy <= 0 {
+		w.ShieldEnergy = 0
+		w.shieldDrained = true
+	}
+	if w.ShieldEnergy > 1 {
+		w.ShieldEnergy = 1
+	}
+
+}
+
+// shipProtected reports whether the ship would shrug off a hit right now.
+func (w *World) shipProtected() bool {
+
+	return w.invulnerable > 0 || w.ShieldUp
+
+}
@@ -0,0 +1,90 @@
+package sim
+
+// Once destroyed, the ship waits at least respawnDelay seconds and then until
+// nothing is within respawnClearance of the centre before it comes back.
+const respawnDelay = 2.0
+const respawnClearance = 120
+
+func (w *World) destroyShip() {
+
+	ship := &w.Entities[0]
+	ship.Hidden = true
+	ship.Dx, ship.Dy = 0, 0
+
+	w.emit(ShipDestroyed, ship.X, ship.Y)
+
+	w.Lives--
+	if w.Lives <= 0 {
+		w.GameOver = true
+		return
+	}
+
+	w.respawnTimer = respawnDelay
+
+}
+
+// clearOf reports whether e could be placed without touching anything already in play.
+func (w *World) clearOf(e Entity) bool {
+
+	for _, e2 := range w.Entities {
+		if !e2.Dead && !e2.Hidden && e.collidesWith(e2) {
+			return false
+		}
+	}
+	return true
+
+}
+
+func (w *World) updateShip(dt float64) {
+
+	if w.invulnerable > 0 {
+		w.invulnerable -= dt
+	}
+	if w.hyperspaceCooldown > 0 {
+		w.hyperspaceCooldown -= dt
+	}
+
+	ship := &w.Entities[0]
+
+	if w.hyperspace > 0 {
+		w.hyperspace -= dt
+		if w.hyperspace <= 0 {
+			w.leaveHyperspace()
+		}
+		return
+	}
+
+	if !ship.Hidden || w.GameOver {
+		return
+	}
+
+	w.respawnTimer -= dt
+	if w.respawnTimer > 0 {
+		return
+	}
+
+	probe := Entity{
+		X:      ScreenWidth / 2,
+		Y:      ScreenHeight / 2,
+		Radius: respawnClearance,
+	}
+	if !w.clearOf(probe) {
+		return
+	}
+
+	ship.X, ship.Y = probe.X, probe.Y
+	ship.Px, ship.Py = probe.X, probe.Y
+	ship.Angle, ship.pangle = 0, 0
+	ship.Hidden = false
+
+	w.invulnerable = w.Config.Invulnerability
+
+}
+
+// Blinking reports whether the ship should be left out of this frame while it
+// flashes during its invulnerability.
+func (w *World) Blinking() bool {
+
+	return w.invulnerable > 0 && int(w.invulnerable*8)%2 == 1
+
+}
@@ -0,0 +1,104 @@
+package sim
+
+import "math"
+
+const firstWaveAsteroids = 20
+const extraAsteroidsPerWave = 2
+const maxWaveAsteroids = 40
+
+// Each wave after the first makes asteroids waveSpeedup faster, both as they
+// spawn and in the cap on how fast collisions can send them.
+const waveSpeedup = 0.15
+const baseAsteroidSpeedCap = 128
+
+const wavePause = 3.0
+const waveBannerTime = 2.0
+
+func (w *World) startWave(wave int) {
+
+	w.Wave = wave
+	w.waveBanner = waveBannerTime
+
+	speed := (1 + waveSpeedup*float64(wave-1)) * w.Config.Difficulty.spec().speed
+	w.asteroidSpeedCap = baseAsteroidSpeedCap * speed
+
+	count := firstWaveAsteroids + extraAsteroidsPerWave*(wave-1)
+	if count > maxWaveAsteroids {
+		count = maxWaveAsteroids
+	}
+
+	r := w.rng
+
+	for i := 1; i <= count; i++ {
+
+		m := w.pickMaterial()
+
+		e := Entity{
+			EntityType: Asteroid,
+			X:          r.Float64() * ScreenWidth,
+			Y:          r.Float64() * ScreenHeight,
+			Dx:         (r.Float64()*100 - 50) * speed,
+			Dy:         (r.Float64()*100 - 50) * speed,
+			Angle:      r.Float64() * 2 * math.Pi,
+			spin:       r.Float64() - 0.5,
+			Scale:      0.1,
+			Radius:     45,
+			Material:   m,
+			hp:         materials[m].hits,
+		}
+
+		if w.Config.VectorAsteroids {
+			e.Shape = w.rockShape(e.Radius)
+		}
+
+		for !w.clearOf(e) || e.separation(w.Entities[0]) < respawnClearance+e.Radius {
+			e.X = r.Float64() * ScreenWidth
+			e.Y = r.Float64() * ScreenHeight
+		}
+
+		w.Spawn(e)
+
+	}
+
+}
+
+// updateWave starts the pause once the last asteroid has gone, and the next
+// wave once the pause is over.
+func (w *World) updateWave(dt float64) {
+
+	if w.waveBanner > 0 {
+		w.waveBanner -= dt
+	}
+
+	if w.GameOver {
+		return
+	}
+
+	if w.wavePause > 0 {
+		w.wavePause -= dt
+		if w.wavePause <= 0 {
+			w.startWave(w.Wave + 1)
+		}
+		return
+	}
+
+	for _, e := range w.Entities {
+		if e.EntityType == Asteroid {
+			return
+		}
+	}
+
+	w.wavePause = wavePause
+
+}
+
+// ShowWaveBanner reports whether the wave number should be on screen: during the
+// pause before a wave and for a short while after it starts.
+func (w *World) ShowWaveBanner() (int, bool) {
+
+	if w.wavePause > 0 {
+		return w.Wave + 1, true
+	}
+	return w.Wave, w.waveBanner > 0
+
+}
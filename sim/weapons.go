@@ -0,0 +1,329 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// WeaponDef describes a weapon entirely as data, so new ones can be added or
+// tuned without code. Kind picks how it fires: "bolt" shots fly straight,
+// "missile" shots steer towards the nearest target, "mine" shots sit still and
+// "beam" hits instantly along a line out to Range.
+//
+// Ammo of zero means unlimited. Heat is added per volley; at 1 the weapon
+// overheats and won't fire again until it has cooled right down.
+type WeaponDef struct {
+	Name     string  `json:"name"`
+	Kind     string  `json:"kind"`
+	Cooldown float64 `json:"cooldown"`
+	Speed    float64 `json:"speed"`
+	Lifetime float64 `json:"lifetime"`
+	Radius   float64 `json:"radius"`
+	Pellets  int     `json:"pellets"`
+	Spread   float64 `json:"spread"`
+	Ammo     int     `json:"ammo"`
+	Heat     float64 `json:"heat"`
+	Cooling  float64 `json:"cooling"`
+	Turn     float64 `json:"turn"`
+	Range    float64 `json:"range"`
+	Capped   bool    `json:"capped"`
+}
+
+var defaultWeapons = []WeaponDef{
+	{Name: "Blaster", Kind: "bolt", Cooldown: 0.2, Speed: 500, Lifetime: 1.2, Radius: 10, Pellets: 1, Capped: true},
+	{Name: "Shotgun", Kind: "bolt", Cooldown: 0.6, Speed: 450, Lifetime: 0.5, Radius: 8, Pellets: 5, Spread: 0.6, Heat: 0.25, Cooling: 0.5},
+	{Name: "Laser", Kind: "beam", Cooldown: 0.05, Range: 400, Heat: 0.06, Cooling: 0.4},
+	{Name: "Missiles", Kind: "missile", Cooldown: 0.5, Speed: 300, Lifetime: 3, Radius: 10, Pellets: 1, Ammo: 20, Turn: 3},
+	{Name: "Mines", Kind: "mine", Cooldown: 0.5, Lifetime: 12, Radius: 14, Pellets: 1, Ammo: 10},
+}
+
+// Weapon is anything the ship can carry and fire.
+type Weapon interface {
+	Def() WeaponDef
+	Ready() bool
+	Fire(w *World, ship Entity)
+	Cool(dt float64)
+	Heat() float64
+	Ammo() int
+}
+
+// RapidFire shortens a weapon's cooldown to this fraction of its usual length.
+const rapidFireFactor = 0.4
+
+func newWeapon(def WeaponDef) Weapon {
+
+	base := weaponBase{def: def, ammo: def.Ammo}
+
+	if def.Kind == "beam" {
+		return &beam{base}
+	}
+	return &gun{base}
+
+}
+
+// weaponBase keeps the ammo and heat that every kind of weapon shares.
+type weaponBase struct {
+	def        WeaponDef
+	ammo       int
+	heat       float64
+	overheated bool
+}
+
+func (b *weaponBase) Def() WeaponDef { return b.def }
+func (b *weaponBase) Heat() float64  { return b.heat }
+func (b *weaponBase) Ammo() int      { return b.ammo }
+
+func (b *weaponBase) Ready() bool {
+
+	return !b.overheated && (b.def.Ammo == 0 || b.ammo > 0)
+
+}
+
+func (b *weaponBase) Cool(dt float64) {
+
+	b.heat = math.Max(0, b.heat-b.def.Cooling*dt)
+	if b.heat == 0 {
+		b.overheated = false
+	}
+
+}
+
+// spend uses up one volley's worth of ammo and heat.
+func (b *weaponBase) spend() {
+
+	if b.def.Ammo > 0 {
+		b.ammo--
+	}
+
+	b.heat += b.def.Heat
+	if b.heat >= 1 {
+		b.heat = 1
+		b.overheated = true
+	}
+
+}
+
+// gun fires projectiles: a fan of Pellets spread evenly across Spread radians.
+type gun struct {
+	weaponBase
+}
+
+func (g *gun) Fire(w *World, ship Entity) {
+
+	g.spend()
+
+	def := g.def
+
+	var angles []float64
+	for p := 0; p < def.Pellets; p++ {
+		offset := 0.0
+		if def.Pellets > 1 {
+			offset = def.Spread * (float64(p)/float64(def.Pellets-1) - 0.5)
+		}
+		angles = append(angles, offset)
+		if w.poweredUp(SpreadShot) {
+			angles = append(angles, offset-spreadAngle, offset+spreadAngle)
+		}
+	}
+
+	for _, offset := range angles {
+
+		angle := ship.Angle + offset
+		projDx := -math.Sin(angle)
+		projDy := math.Cos(angle)
+
+		w.Spawn(Entity{
+			EntityType: Projectile,
+			Owner:      ship.ID,
+			X:          ship.X + ship.Radius*projDx,
+			Y:          ship.Y + ship.Radius*projDy,
+			Dx:         def.Speed * projDx,
+			Dy:         def.Speed * projDy,
+			Angle:      angle,
+			Radius:     def.Radius,
+			Scale:      def.Radius / 200,
+			TTL:        def.Lifetime,
+			pierce:     w.poweredUp(PiercingShots),
+			Homing:     def.Turn,
+			Mine:       def.Kind == "mine",
+			capped:     def.Capped,
+		})
+
+	}
+
+}
+
+// beam hits the first target along its line straight away, or every target on
+// it when piercing.
+type beam struct {
+	weaponBase
+}
+
+func (b *beam) Fire(w *World, ship Entity) {
+
+	b.spend()
+
+	dirX, dirY := -math.Sin(ship.Angle), math.Cos(ship.Angle)
+	x0, y0 := ship.X+ship.Radius*dirX, ship.Y+ship.Radius*dirY
+
+	origin := Entity{X: x0, Y: y0}
+	reach := b.def.Range
+
+	type crossing struct {
+		target int
+		at     float64
+	}
+	var hits []crossing
+
+	for i, e := range w.Entities {
+
+		if e.Dead || e.Hidden || (e.EntityType != Asteroid && e.EntityType != Saucer) {
+			continue
+		}
+
+		ox, oy := e.offset(origin)
+		along := ox*dirX + oy*dirY
+		across := ox*dirY - oy*dirX
+		if math.Abs(across) > e.Radius {
+			continue
+		}
+
+		half := math.Sqrt(e.Radius*e.Radius - across*across)
+		at := along - half
+		if along+half < 0 || at > reach {
+			continue
+		}
+
+		if len(e.Shape) > 0 {
+			var crosses bool
+			at, crosses = rayPolygon(e.outline(ox, oy), dirX, dirY)
+			if !crosses || at > reach {
+				continue
+			}
+		}
+		hits = append(hits, crossing{target: i, at: math.Max(0, at)})
+
+	}
+
+	pierce := w.poweredUp(PiercingShots)
+
+	sort.SliceStable(hits, func(n, m int) bool { return hits[n].at < hits[m].at })
+
+	if !pierce && len(hits) > 1 {
+		hits = hits[:1]
+	}
+	if !pierce && len(hits) == 1 {
+		reach = hits[0].at
+	}
+
+	shot := Entity{
+		EntityType: Projectile,
+		Owner:      ship.ID,
+		Dx:         dirX * 1000,
+		Dy:         dirY * 1000,
+	}
+
+	// every hit is worked out before any lands, so skip a target that an earlier
+	// hit has already finished off
+	var spawned []Entity
+	for _, h := range hits {
+		if w.Entities[h.target].Dead {
+			continue
+		}
+		spawned = append(spawned, w.hit(shot, h.target)...)
+	}
+	for _, e := range spawned {
+		w.Spawn(e)
+	}
+
+	w.emitLine(LaserFired, x0, y0, x0+dirX*reach, y0+dirY*reach)
+
+}
+
+// guide turns homing missiles towards the nearest asteroid or saucer, by no more
+// than their turn rate allows.
+func (w *World) guide(dt float64) {
+
+	for i := range w.Entities {
+
+		m := &w.Entities[i]
+		if m.EntityType != Projectile || m.Homing <= 0 || m.Dead {
+			continue
+		}
+
+		best := math.Inf(1)
+		var tx, ty float64
+		for _, e := range w.Entities {
+			if e.Dead || e.Hidden || (e.EntityType != Asteroid && e.EntityType != Saucer) {
+				continue
+			}
+			ox, oy := e.offset(*m)
+			if d := ox*ox + oy*oy; d < best {
+				best, tx, ty = d, ox, oy
+			}
+		}
+		if math.IsInf(best, 1) {
+			continue
+		}
+
+		heading := math.Atan2(m.Dy, m.Dx)
+		turn := WrapDelta(math.Atan2(ty, tx)-heading, 2*math.Pi)
+		limit := m.Homing * dt
+		turn = math.Max(-limit, math.Min(limit, turn))
+
+		speed := m.velocity()
+		heading += turn
+		m.Dx = speed * math.Cos(heading)
+		m.Dy = speed * math.Sin(heading)
+		m.Angle = heading - math.Pi/2
+
+	}
+
+}
+
+// switchWeapon cycles through the ship's weapons once per press.
+func (w *World) switchWeapon(input InputState) {
+
+	n := len(w.Weapons)
+
+	if input.NextWeapon && !w.lastInput.NextWeapon {
+		w.CurrentWeapon = (w.CurrentWeapon + 1) % n
+	}
+	if input.PrevWeapon && !w.lastInput.PrevWeapon {
+		w.CurrentWeapon = (w.CurrentWeapon + n - 1) % n
+	}
+
+}
+
+var weaponKinds = map[string]bool{"bolt": true, "missile": true, "mine": true, "beam": true}
+
+func LoadWeaponDefs(path string) ([]WeaponDef, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []WeaponDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("%s: no weapons defined", path)
+	}
+
+	for _, def := range defs {
+		switch {
+		case !weaponKinds[def.Kind]:
+			return nil, fmt.Errorf("%s: weapon %q has unknown kind %q", path, def.Name, def.Kind)
+		case def.Kind != "beam" && def.Pellets < 1:
+			return nil, fmt.Errorf("%s: weapon %q fires no pellets", path, def.Name)
+		}
+	}
+
+	return defs, nil
+
+}
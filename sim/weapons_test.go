@@ -0,0 +1,36 @@
+package sim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWeaponDefsRejectsBadWeapons(t *testing.T) {
+
+	cases := map[string]string{
+		"no weapons":   `[]`,
+		"unknown kind": `[{"name": "Flamer", "kind": "flame", "pellets": 1}]`,
+		"no pellets":   `[{"name": "Blank", "kind": "bolt"}]`,
+	}
+
+	for name, defs := range cases {
+		path := filepath.Join(t.TempDir(), "weapons.json")
+		if err := os.WriteFile(path, []byte(defs), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadWeaponDefs(path); err == nil {
+			t.Errorf("%s: loaded without an error", name)
+		}
+	}
+
+	// a beam has no pellets to fire
+	path := filepath.Join(t.TempDir(), "weapons.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "Laser", "kind": "beam", "range": 400}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWeaponDefs(path); err != nil {
+		t.Error(err)
+	}
+
+}
@@ -0,0 +1,641 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+const ScreenWidth = 1024
+const ScreenHeight = 768
+
+// Entities wrap once they are 50 px beyond the edge of the screen, so the
+// playfield is a torus slightly larger than the window.
+const PlayfieldWidth = ScreenWidth + 100
+const PlayfieldHeight = ScreenHeight + 100
+
+const TickRate = 120
+const TickLength = 1.0 / TickRate
+
+const shipThrust = 1500
+
+type EntityType int
+
+const (
+	Ship       EntityType = 1
+	Asteroid   EntityType = 2
+	Projectile EntityType = 3
+	Saucer     EntityType = 4
+	Pickup     EntityType = 5
+)
+
+type Entity struct {
+	EntityType
+	ID       int
+	Owner    int
+	X        float64
+	Y        float64
+	Dx       float64
+	Dy       float64
+	Radius   float64
+	Angle    float64
+	spin     float64
+	Scale    float64
+	Px       float64
+	Py       float64
+	pangle   float64
+	TTL      float64
+	Dead     bool
+	Hidden   bool
+	saucer   saucerKind
+	turn     float64
+	reload   float64
+	Pickup   PickupKind
+	pierce   bool
+	immune   float64
+	Material Material
+	hp       int
+	Shape    []Point
+	mask     *AlphaMask
+	Homing   float64
+	Mine     bool
+	capped   bool
+}
+
+// WrapDelta folds a distance along one axis of the playfield into the shortest
+// way round, between -size/2 and size/2.
+func WrapDelta(d, size float64) float64 {
+
+	return d - size*math.Floor(d/size+0.5)
+
+}
+
+// offset is the shortest displacement from e2 to e, allowing for the playfield
+// wrapping at its edges.
+func (e Entity) offset(e2 Entity) (float64, float64) {
+
+	return WrapDelta(e.X-e2.X, PlayfieldWidth), WrapDelta(e.Y-e2.Y, PlayfieldHeight)
+
+}
+
+func (e Entity) separation(e2 Entity) float64 {
+
+	dx, dy := e.offset(e2)
+	return math.Sqrt(dx*dx + dy*dy)
+
+}
+
+func (e Entity) collidesWith(e2 Entity) bool {
+
+	return e.separation(e2) <= e.reach()+e2.reach()
+
+}
+
+func (e Entity) velocity() float64 {
+
+	return math.Sqrt(e.Dx*e.Dx + e.Dy*e.Dy)
+
+}
+
+// Lerp blends the entity's position from the previous step towards the current one,
+// going the short way round if it wrapped in between. Headings that come from
+// Atan2 jump by a whole turn as they pass ±π, so angles go the short way too.
+func (e Entity) Lerp(alpha float64) (x, y, angle float64) {
+
+	x = e.Px + WrapDelta(e.X-e.Px, PlayfieldWidth)*alpha
+	y = e.Py + WrapDelta(e.Y-e.Py, PlayfieldHeight)*alpha
+
+	angle = e.pangle + WrapDelta(e.Angle-e.pangle, 2*math.Pi)*alpha
+
+	return x, y, angle
+
+}
+
+// InputState is the set of controls held down for a single step of the world.
+type InputState struct {
+	RotateLeft  bool
+	RotateRight bool
+	Thrust      bool
+	Reverse     bool
+	StrafeLeft  bool
+	StrafeRight bool
+	Fire        bool
+	Defend      bool
+	NextWeapon  bool
+	PrevWeapon  bool
+}
+
+type EventKind int
+
+const (
+	ShipDestroyed EventKind = 1
+	HyperspaceOut EventKind = 2
+	HyperspaceIn  EventKind = 3
+	LaserFired    EventKind = 4
+	Explosion     EventKind = 5
+	ShotHit       EventKind = 6
+	AsteroidSplit EventKind = 7
+	Thrusting     EventKind = 8
+)
+
+// Event marks something that happened during a step which is worth showing,
+// but has no further effect on the game. Events along a line run from X, Y to X2, Y2.
+type Event struct {
+	Kind EventKind
+	X    float64
+	Y    float64
+	X2   float64
+	Y2   float64
+}
+
+// World holds every entity in play and advances the game without needing a window.
+// The player's ship is always Entities[0]. Every random draw goes through rng, so two
+// worlds built from the same seed and fed the same inputs play out identically.
+type World struct {
+	Config             Config
+	Entities           []Entity
+	nextID             int
+	fireCooldown       float64
+	Lives              int
+	Score              int
+	nextExtraLife      int
+	Wave               int
+	wavePause          float64
+	waveBanner         float64
+	asteroidSpeedCap   float64
+	saucerTimer        float64
+	hyperspace         float64
+	hyperspaceCooldown float64
+	Events             []Event
+	ShieldUp           bool
+	ShieldEnergy       float64
+	shieldDrained      bool
+	PowerUps           map[PickupKind]float64
+	Weapons            []Weapon
+	CurrentWeapon      int
+	lastInput          InputState
+	respawnTimer       float64
+	invulnerable       float64
+	GameOver           bool
+	seed               int64
+	rng                *rand.Rand
+	grid               *spatialHash
+	impacts            []impact
+}
+
+// impact is the earliest target a projectile will reach this step.
+type impact struct {
+	target int
+	time   float64
+}
+
+func NewWorld(seed int64, config Config) *World {
+
+	w := &World{
+		Config:        config,
+		Lives:         config.Lives + config.Difficulty.spec().lives,
+		nextExtraLife: config.ExtraLifeEvery,
+		invulnerable:  config.Invulnerability,
+		saucerTimer:   config.SaucerInterval * config.Difficulty.spec().saucers,
+		ShieldEnergy:  1,
+		PowerUps:      map[PickupKind]float64{},
+		seed:          seed,
+		rng:           rand.New(rand.NewSource(seed)),
+		grid:          newSpatialHash(cellSize),
+	}
+
+	if w.Lives < 1 {
+		w.Lives = 1
+	}
+
+	// a config from before weapons were configurable has none, so it gets the usual set
+	if len(w.Config.Weapons) == 0 {
+		w.Config.Weapons = defaultWeapons
+	}
+	for _, def := range w.Config.Weapons {
+		w.Weapons = append(w.Weapons, newWeapon(def))
+	}
+
+	w.Spawn(Entity{
+		EntityType: Ship,
+		X:          float64(ScreenWidth / 2),
+		Y:          float64(ScreenHeight / 2),
+		Dx:         0,
+		Dy:         0,
+		Angle:      0.0,
+		Radius:     30,
+		Scale:      0.2,
+	})
+
+	w.startWave(1)
+
+	return w
+
+}
+
+func (w *World) emit(kind EventKind, x, y float64) {
+
+	w.Events = append(w.Events, Event{Kind: kind, X: x, Y: y})
+
+}
+
+func (w *World) emitLine(kind EventKind, x, y, x2, y2 float64) {
+
+	w.Events = append(w.Events, Event{Kind: kind, X: x, Y: y, X2: x2, Y2: y2})
+
+}
+
+// Spawn adds an entity to the world with a fresh id and no motion to interpolate from.
+func (w *World) Spawn(e Entity) {
+
+	w.nextID++
+	e.ID = w.nextID
+
+	if w.Config.PixelCollision && len(e.Shape) == 0 {
+		e.mask = Masks[e.EntityType]
+	}
+
+	e.Px, e.Py, e.pangle = e.X, e.Y, e.Angle
+	w.Entities = append(w.Entities, e)
+
+}
+
+// Step advances the world by dt seconds with the given controls held.
+// It should always be called with the same dt (TickLength) so that runs are repeatable.
+func (w *World) Step(dt float64, input InputState) {
+
+	for i := range w.Entities {
+		w.Entities[i].Px, w.Entities[i].Py, w.Entities[i].pangle = w.Entities[i].X, w.Entities[i].Y, w.Entities[i].Angle
+	}
+	w.Events = w.Events[:0]
+
+	w.updateShip(dt)
+	w.updatePowerUps(dt)
+	w.jump(input)
+	w.raiseShield(dt, input)
+	w.steer(dt, input)
+	w.magnetise(dt)
+	w.switchWeapon(input)
+	w.fire(dt, input)
+	w.updateSaucers(dt)
+	w.guide(dt)
+	w.collide(dt)
+	w.integrate(dt)
+	w.removeDead()
+	w.updateWave(dt)
+
+	w.lastInput = input
+
+}
+
+func (w *World) steer(dt float64, input InputState) {
+
+	es := w.Entities
+
+	if es[0].Hidden {
+		return
+	}
+
+	if input.RotateLeft {
+		es[0].Angle += 2 * dt
+	}
+	if input.RotateRight {
+		es[0].Angle -= 2 * dt
+	}
+	if input.Thrust {
+		es[0].Dx -= shipThrust * dt * math.Sin(es[0].Angle)
+		es[0].Dy += shipThrust * dt * math.Cos(es[0].Angle)
+		// exhaust runs out from the tail, straight back
+		s, c := math.Sin(es[0].Angle), math.Cos(es[0].Angle)
+		tx, ty := es[0].X+s*es[0].Radius, es[0].Y-c*es[0].Radius
+		w.emitLine(Thrusting, tx, ty, tx+s, ty-c)
+	}
+	if input.Reverse {
+		es[0].Dx += shipThrust * dt * math.Sin(es[0].Angle)
+		es[0].Dy -= shipThrust * dt * math.Cos(es[0].Angle)
+	}
+	if input.StrafeLeft {
+		es[0].Dx -= shipThrust * dt * math.Cos(es[0].Angle)
+		es[0].Dy -= shipThrust * dt * math.Sin(es[0].Angle)
+	}
+	if input.StrafeRight {
+		es[0].Dx += shipThrust * dt * math.Cos(es[0].Angle)
+		es[0].Dy += shipThrust * dt * math.Sin(es[0].Angle)
+	}
+
+}
+
+func (w *World) fire(dt float64, input InputState) {
+
+	w.fireCooldown -= dt
+	for _, wpn := range w.Weapons {
+		wpn.Cool(dt)
+	}
+
+	ship := w.Entities[0]
+	wpn := w.Weapons[w.CurrentWeapon]
+
+	if !input.Fire || ship.Hidden || w.fireCooldown > 0 || !wpn.Ready() {
+		return
+	}
+	if wpn.Def().Capped && w.liveShots(ship.ID) >= w.Config.MaxShots {
+		return
+	}
+
+	w.fireCooldown = wpn.Def().Cooldown
+	if w.poweredUp(RapidFire) {
+		w.fireCooldown *= rapidFireFactor
+	}
+
+	wpn.Fire(w, ship)
+
+}
+
+func (w *World) liveShots(owner int) int {
+
+	n := 0
+	for _, e := range w.Entities {
+		if e.EntityType == Projectile && e.Owner == owner && e.capped && !e.Dead {
+			n++
+		}
+	}
+	return n
+
+}
+
+// collide resolves everything touching at the start of the step. Projectiles are
+// swept along their path for the coming dt instead, and each one hits whichever
+// target it would reach first, so fast shots can't pass through small rocks.
+func (w *World) collide(dt float64) {
+
+	es := w.Entities
+
+	var spawned []Entity
+
+	if cap(w.impacts) < len(es) {
+		w.impacts = make([]impact, len(es))
+	}
+	w.impacts = w.impacts[:len(es)]
+	for i := range w.impacts {
+		w.impacts[i] = impact{target: -1}
+	}
+
+	w.grid.build(es, dt)
+
+	for _, pair := range w.grid.pairs() {
+
+		i, j := pair[0], pair[1]
+
+		if es[i].Dead || es[j].Dead || es[i].Hidden || es[j].Hidden {
+			continue
+		}
+
+		if es[j].EntityType == Projectile {
+			i, j = j, i
+		}
+
+		if es[i].EntityType == Projectile {
+
+			if es[i].immune <= 0 && es[j].EntityType != Projectile && es[j].EntityType != Pickup && es[j].ID != es[i].Owner {
+				t, hit := es[i].timeOfImpact(es[j], dt)
+				if hit && len(es[j].Shape) > 0 {
+					t, hit = sweepShape(es[i], es[j], t, dt)
+				} else if hit && (es[i].mask != nil || es[j].mask != nil) {
+					t, hit = sweepMask(es[i], es[j], t, dt)
+				}
+				if hit && (w.impacts[i].target < 0 || t < w.impacts[i].time) {
+					w.impacts[i] = impact{target: j, time: t}
+				}
+			}
+
+			continue
+
+		}
+
+		nx, ny, depth, touching := contact(es[i], es[j])
+		if !touching {
+			continue
+		}
+
+		if es[i].EntityType == Pickup || es[j].EntityType == Pickup {
+			if i == 0 {
+				w.collect(&es[j])
+			}
+			continue
+		}
+
+		if es[j].EntityType == Saucer {
+			i, j = j, i
+			nx, ny = -nx, -ny
+		}
+
+		switch {
+		case es[i].EntityType == Saucer:
+			w.destroySaucer(&es[i], j == 0)
+			if es[j].EntityType == Saucer {
+				es[j].Dead = true
+			}
+			if j == 0 && !w.shipProtected() {
+				w.destroyShip()
+			}
+		case i == 0 && es[j].EntityType == Asteroid && !w.shipProtected():
+			w.destroyShip()
+		default:
+			resolveCollision(&es[i], &es[j], nx, ny, depth)
+		}
+
+	}
+
+	for i, hit := range w.impacts {
+
+		if hit.target < 0 || es[hit.target].Dead || es[hit.target].Hidden {
+			continue
+		}
+
+		if es[i].pierce {
+			es[i].immune = pierceTime
+		} else {
+			es[i].Dead = true
+		}
+
+		spawned = append(spawned, w.hit(es[i], hit.target)...)
+
+	}
+
+	for _, e := range spawned {
+		w.Spawn(e)
+	}
+
+}
+
+// hit lands shot p on Entities[target], returning anything that breaks off it to be
+// spawned once the caller is done with the entity list.
+func (w *World) hit(p Entity, target int) []Entity {
+
+	es := w.Entities
+	t := &es[target]
+	byPlayer := p.Owner == es[0].ID
+
+	// sparks fly back the way the shot came, from the side of the target it struck
+	hx, hy := t.X, t.Y
+	if v := p.velocity(); v > 0 {
+		ux, uy := p.Dx/v, p.Dy/v
+		hx, hy = t.X-ux*t.Radius, t.Y-uy*t.Radius
+		w.emitLine(ShotHit, hx, hy, hx-ux, hy-uy)
+	} else {
+		w.emit(ShotHit, hx, hy)
+	}
+
+	switch {
+	case t.EntityType == Asteroid:
+		spec := materials[t.Material]
+		t.hp--
+		if t.hp > 0 {
+			return nil
+		}
+		if byPlayer {
+			w.award(w.asteroidPoints(*t))
+		}
+		if spec.blast > 0 {
+			return w.explode(target, p.Owner)
+		}
+		w.emit(AsteroidSplit, t.X, t.Y)
+		spawned := splitAsteroid(p, t)
+		if t.Dead {
+			spawned = append(spawned, w.dropPickup(*t)...)
+		}
+		return spawned
+	case t.EntityType == Saucer:
+		w.destroySaucer(t, byPlayer)
+	case target == 0 && !w.shipProtected():
+		w.destroyShip()
+	}
+
+	return nil
+
+}
+
+// splitAsteroid breaks an asteroid hit by a projectile into pieces flying apart
+// across the shot's path, keeping the first piece in place of the original and
+// returning the rest, or marks it dead if it is already too small to split.
+func splitAsteroid(p Entity, a *Entity) []Entity {
+
+	if a.Radius < 20 {
+		a.Dead = true
+		return nil
+	}
+
+	// a shot with no speed of its own, like a mine, splits the rock along its own path
+	if p.velocity() == 0 {
+		p = *a
+	}
+
+	v := p.velocity()
+	dx, dy := 0.0, 0.0
+	if v > 0 {
+		dx = p.Dx / v
+		dy = p.Dy / v
+	}
+
+	spec := materials[a.Material]
+
+	a.hp = spec.hits
+
+	if len(a.Shape) > 0 {
+		return cutAsteroid(a, dx, dy, v, spec.fragments, spec.shrink)
+	}
+
+	a.Scale *= spec.shrink
+	a.Radius *= spec.shrink
+
+	var fragments []Entity
+
+	for k := 0; k < spec.fragments; k++ {
+
+		turn := 2 * math.Pi * float64(k) / float64(spec.fragments)
+		fx, fy := -dy*v*2, dx*v*2
+
+		f := *a
+		f.Dx = fx*math.Cos(turn) - fy*math.Sin(turn)
+		f.Dy = fx*math.Sin(turn) + fy*math.Cos(turn)
+		if k%2 == 1 {
+			f.Angle = -a.Angle
+			f.spin = -a.spin
+		}
+
+		if k == 0 {
+			*a = f
+		} else {
+			fragments = append(fragments, f)
+		}
+
+	}
+
+	return fragments
+
+}
+
+func (w *World) removeDead() {
+
+	live := w.Entities[:0]
+	for _, e := range w.Entities {
+		if !e.Dead {
+			live = append(live, e)
+		}
+	}
+	w.Entities = live
+
+}
+
+func (w *World) integrate(dt float64) {
+
+	es := w.Entities
+
+	for i := range es {
+
+		es[i].X += es[i].Dx * dt
+		es[i].Y += es[i].Dy * dt
+		es[i].Angle += es[i].spin * dt
+
+		if es[i].immune > 0 {
+			es[i].immune -= dt
+		}
+
+		if es[i].TTL > 0 {
+			es[i].TTL -= dt
+			if es[i].TTL <= 0 {
+				es[i].Dead = true
+			}
+		}
+
+		if es[i].X < -50 {
+			es[i].X += ScreenWidth + 100
+		}
+		if es[i].Y < -50 {
+			es[i].Y += ScreenHeight + 100
+		}
+		if es[i].X > ScreenWidth+50 {
+			es[i].X -= ScreenWidth + 100
+		}
+		if es[i].Y > ScreenHeight+50 {
+			es[i].Y -= ScreenHeight + 100
+		}
+
+		v := es[i].velocity()
+		if es[i].EntityType == Ship {
+			if v > 256 {
+				es[i].Dx *= 256 / v
+				es[i].Dy *= 256 / v
+			} else {
+				es[i].Dx *= 1 - dt
+				es[i].Dy *= 1 - dt
+			}
+		} else if es[i].EntityType == Asteroid {
+			if v > w.asteroidSpeedCap {
+				es[i].Dx *= w.asteroidSpeedCap / v
+				es[i].Dy *= w.asteroidSpeedCap / v
+			}
+		}
+
+	}
+
+}
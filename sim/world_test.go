@@ -0,0 +1,439 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+// play runs a world for the given number of ticks with a fixed pattern of controls.
+func play(w *World, ticks int) {
+
+	for i := 0; i < ticks; i++ {
+		w.Step(TickLength, InputState{
+			Fire:       true,
+			RotateLeft: i%300 < 150,
+			Thrust:     i%200 < 20,
+		})
+	}
+
+}
+
+func TestSameSeedPlaysTheSame(t *testing.T) {
+
+	a := NewWorld(42, DefaultConfig())
+	b := NewWorld(42, DefaultConfig())
+
+	play(a, TickRate*30)
+	play(b, TickRate*30)
+
+	if len(a.Entities) != len(b.Entities) {
+		t.Fatalf("worlds drifted apart: %d and %d entities", len(a.Entities), len(b.Entities))
+	}
+	for i := range a.Entities {
+		if a.Entities[i].X != b.Entities[i].X || a.Entities[i].Y != b.Entities[i].Y {
+			t.Fatalf("entity %d at %v,%v and %v,%v", i, a.Entities[i].X, a.Entities[i].Y, b.Entities[i].X, b.Entities[i].Y)
+		}
+	}
+
+}
+
+func TestThrustMovesTheShip(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = w.Entities[:1]
+
+	for i := 0; i < TickRate/2; i++ {
+		w.Step(TickLength, InputState{Thrust: true})
+	}
+
+	if ship := w.Entities[0]; ship.Dy <= 0 || ship.Y <= ScreenHeight/2 {
+		t.Fatalf("ship at y %v moving %v after thrusting up", ship.Y, ship.Dy)
+	}
+
+}
+
+func TestEntitiesWrapAroundTheScreen(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = []Entity{w.Entities[0], {EntityType: Asteroid, X: ScreenWidth + 49, Y: 100, Dx: 100, Radius: 45}}
+
+	w.Step(0.1, InputState{})
+
+	if x := w.Entities[1].X; x > 0 {
+		t.Fatalf("asteroid at x %v didn't wrap to the left edge", x)
+	}
+
+}
+
+func TestShotsSplitAsteroids(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = []Entity{w.Entities[0], {EntityType: Asteroid, X: ScreenWidth / 2, Y: ScreenHeight/2 + 200, Radius: 45, Scale: 0.1}}
+
+	for i := 0; i < TickRate; i++ {
+		w.Step(TickLength, InputState{Fire: i == 0})
+	}
+
+	asteroids := 0
+	for _, e := range w.Entities {
+		if e.EntityType == Asteroid {
+			asteroids++
+			if e.Radius >= 45 {
+				t.Errorf("asteroid still has radius %v", e.Radius)
+			}
+		}
+	}
+	if asteroids != 2 {
+		t.Fatalf("got %d asteroids after the hit, want 2", asteroids)
+	}
+
+}
+
+func TestLerpBlendsBetweenTicks(t *testing.T) {
+
+	e := Entity{X: 110, Y: 200, Angle: 1, Px: 100, Py: 200}
+	if x, y, angle := e.Lerp(0.5); x != 105 || y != 200 || angle != 0.5 {
+		t.Errorf("halfway between ticks drawn at %v,%v turned %v", x, y, angle)
+	}
+
+	// an entity that just wrapped carries on the short way round rather than
+	// sweeping back across the screen
+	e = Entity{X: -45, Y: 200, Px: ScreenWidth + 45, Py: 200}
+	if x, _, _ := e.Lerp(0.5); x != ScreenWidth+50 {
+		t.Errorf("wrapped entity drawn at %v", x)
+	}
+
+	// a missile whose heading just passed from π to -π keeps turning the same way
+	e = Entity{Angle: -math.Pi + 0.1, pangle: math.Pi - 0.1}
+	if _, _, angle := e.Lerp(0.5); math.Abs(WrapDelta(angle-math.Pi, 2*math.Pi)) > 1e-9 {
+		t.Errorf("heading swung round to %v", angle)
+	}
+
+}
+
+// A shot fast enough to jump clean over a small rock in one step must still hit
+// it, whatever the step length and even when the rock sits across the wrap.
+func TestFastShotsHitSmallTargets(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		rockY  float64
+		shotY  float64
+		shotDY float64
+	}{
+		{"open space", 200, 80, 500},
+		{"across the wrap", -45, ScreenHeight + 20, 500},
+	}
+
+	for _, c := range cases {
+		for _, dt := range []float64{TickLength, 0.05, 0.1, 0.25} {
+
+			w := NewWorld(1, DefaultConfig())
+			w.Entities = w.Entities[:1]
+			w.Spawn(Entity{EntityType: Asteroid, X: 300, Y: c.rockY, Radius: 5})
+			w.Spawn(Entity{EntityType: Projectile, X: 300, Y: c.shotY, Dy: c.shotDY, Radius: 5, TTL: 2})
+
+			hit := false
+			for i := 0; i < int(1/dt) && !hit; i++ {
+				w.Step(dt, InputState{})
+				hit = true
+				for _, e := range w.Entities {
+					if e.EntityType == Asteroid {
+						hit = false
+					}
+				}
+			}
+
+			if !hit {
+				t.Errorf("%s: shot missed at dt %v", c.name, dt)
+			}
+
+		}
+	}
+
+}
+
+func TestShotsExpireAndAreCapped(t *testing.T) {
+
+	config := DefaultConfig()
+	w := NewWorld(1, config)
+	w.Entities = w.Entities[:1]
+
+	most := 0
+	for i := 0; i < TickRate*2; i++ {
+		w.Step(TickLength, InputState{Fire: true})
+		if n := w.liveShots(w.Entities[0].ID); n > most {
+			most = n
+		}
+	}
+	if most != config.MaxShots {
+		t.Errorf("at most %d shots in flight, want %d", most, config.MaxShots)
+	}
+
+	for i := 0; i < int(config.Weapons[0].Lifetime*TickRate)+1; i++ {
+		w.Step(TickLength, InputState{})
+	}
+	if n := w.liveShots(w.Entities[0].ID); n > 0 {
+		t.Errorf("%d shots still in flight after their lifetime", n)
+	}
+
+}
+
+func TestLosingEveryLifeEndsTheGame(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+
+	for lives := w.Lives; lives > 0; lives-- {
+		if w.GameOver {
+			t.Fatalf("game over with %d lives left", lives)
+		}
+		w.destroyShip()
+		for i := 0; i < TickRate*10 && w.Entities[0].Hidden && !w.GameOver; i++ {
+			w.Entities = w.Entities[:1] // clear the field so the ship can respawn
+			w.Step(TickLength, InputState{})
+		}
+	}
+
+	if !w.GameOver {
+		t.Fatal("game still going with no lives left")
+	}
+
+}
+
+func TestShipWaitsForAClearCentreToRespawn(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = w.Entities[:1]
+	w.destroyShip()
+	w.Spawn(Entity{EntityType: Asteroid, X: ScreenWidth / 2, Y: ScreenHeight / 2, Radius: 45})
+
+	for i := 0; i < TickRate*(respawnDelay+1); i++ {
+		w.Step(TickLength, InputState{})
+	}
+	if !w.Entities[0].Hidden {
+		t.Fatal("ship respawned on top of an asteroid")
+	}
+
+	w.Entities = w.Entities[:1]
+	w.Step(TickLength, InputState{})
+	if w.Entities[0].Hidden || w.invulnerable <= 0 {
+		t.Fatalf("ship hidden %v, invulnerable for %v once the centre cleared", w.Entities[0].Hidden, w.invulnerable)
+	}
+
+}
+
+func TestScoringPassesExtraLifeMilestones(t *testing.T) {
+
+	config := DefaultConfig()
+	w := NewWorld(1, config)
+	lives := w.Lives
+
+	w.award(config.ExtraLifeEvery - 1)
+	if w.Lives != lives {
+		t.Fatalf("extra life before the first milestone")
+	}
+
+	// one big award can pass more than one milestone
+	w.award(config.ExtraLifeEvery + 1)
+	if w.Lives != lives+2 {
+		t.Fatalf("%d lives after passing two milestones, want %d", w.Lives, lives+2)
+	}
+
+}
+
+func TestClearingAWaveStartsTheNext(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = w.Entities[:1]
+
+	for i := 0; i < TickRate*int(wavePause+1) && w.Wave == 1; i++ {
+		w.Step(TickLength, InputState{})
+	}
+
+	if w.Wave != 2 {
+		t.Fatalf("still on wave %d", w.Wave)
+	}
+
+	asteroids := 0
+	for _, e := range w.Entities {
+		if e.EntityType == Asteroid {
+			asteroids++
+		}
+	}
+	if want := firstWaveAsteroids + extraAsteroidsPerWave; asteroids != want {
+		t.Fatalf("wave 2 has %d asteroids, want %d", asteroids, want)
+	}
+
+}
+
+func TestSmallSaucersAimAtTheShip(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = w.Entities[:1]
+	w.Spawn(Entity{EntityType: Saucer, saucer: SmallSaucer, X: 100, Y: 100, Radius: 18})
+
+	for k := 0; k < 20; k++ {
+		w.saucerFire(1)
+		shot := w.Entities[len(w.Entities)-1]
+		dx, dy := w.Entities[0].offset(w.Entities[1])
+		miss := math.Abs(WrapDelta(math.Atan2(shot.Dy, shot.Dx)-math.Atan2(dy, dx), 2*math.Pi))
+		if miss > saucerSpecs[SmallSaucer].accuracy+1e-9 {
+			t.Fatalf("shot %v radians wide of the ship", miss)
+		}
+	}
+
+}
+
+func TestHyperspaceJumpsAndCoolsDown(t *testing.T) {
+
+	config := DefaultConfig()
+	config.HyperspaceRisk = 0
+	w := NewWorld(1, config)
+	w.Entities = w.Entities[:1]
+	lives := w.Lives
+
+	w.Step(TickLength, InputState{Defend: true})
+	for i := 0; i < TickRate; i++ {
+		w.Step(TickLength, InputState{})
+	}
+
+	ship := w.Entities[0]
+	if ship.Hidden || ship.X == ScreenWidth/2 && ship.Y == ScreenHeight/2 || w.Lives != lives {
+		t.Fatalf("ship hidden %v at %v,%v with %d lives after a safe jump", ship.Hidden, ship.X, ship.Y, w.Lives)
+	}
+
+	// still cooling down, so a second jump does nothing
+	w.Step(TickLength, InputState{Defend: true})
+	if w.Entities[0].Hidden {
+		t.Fatal("jumped again during the cooldown")
+	}
+
+}
+
+func TestEmptyShieldStaysDownUntilRecharged(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Defence = ShieldDefence
+	w := NewWorld(1, config)
+	w.Entities = w.Entities[:1]
+
+	for w.ShieldEnergy > 0 {
+		w.Step(TickLength, InputState{Defend: true})
+	}
+
+	up := 0
+	for i := 0; i < TickRate; i++ {
+		w.Step(TickLength, InputState{Defend: true})
+		if w.ShieldUp {
+			up++
+		}
+	}
+	if up > 0 {
+		t.Fatalf("shield up on %d of %d ticks held with no energy", up, TickRate)
+	}
+
+	for w.ShieldEnergy < shieldRestart {
+		w.Step(TickLength, InputState{Defend: true})
+	}
+	w.Step(TickLength, InputState{Defend: true})
+	if !w.ShieldUp {
+		t.Fatalf("shield still down with %v energy", w.ShieldEnergy)
+	}
+
+}
+
+func TestShieldRefillsOnlyDropForShields(t *testing.T) {
+
+	for _, defence := range []defence{HyperspaceDefence, ShieldDefence} {
+
+		config := DefaultConfig()
+		config.Defence = defence
+		config.PickupChance = 1
+		w := NewWorld(1, config)
+
+		refills := 0
+		for i := 0; i < 100; i++ {
+			if w.dropPickup(Entity{})[0].Pickup == ShieldRefill {
+				refills++
+			}
+		}
+
+		if (defence == ShieldDefence) != (refills > 0) {
+			t.Errorf("%d shield refills dropped flying with %v", refills, defence)
+		}
+
+	}
+
+}
+
+func TestWorldWithoutWeaponsUsesDefaults(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Weapons = nil
+
+	w := NewWorld(1, config)
+	w.Step(TickLength, InputState{Fire: true, NextWeapon: true})
+
+	if len(w.Weapons) != len(defaultWeapons) {
+		t.Fatalf("got %d weapons, want %d", len(w.Weapons), len(defaultWeapons))
+	}
+
+}
+
+// A piercing beam works out everything on its line before any of it is hit, so a
+// small rock destroyed by the blast of an explosive one in front only pays out once.
+func TestPiercingBeamThroughABlastPaysOnce(t *testing.T) {
+
+	w := NewWorld(1, DefaultConfig())
+	w.Entities = w.Entities[:1]
+	w.Spawn(Entity{EntityType: Asteroid, Material: Explosive, X: ScreenWidth / 2, Y: ScreenHeight/2 + 100, Radius: 20, hp: 1})
+	w.Spawn(Entity{EntityType: Asteroid, X: ScreenWidth / 2, Y: ScreenHeight/2 + 150, Radius: 15, hp: 1})
+	want := w.asteroidPoints(w.Entities[1]) + w.asteroidPoints(w.Entities[2])
+
+	for k, wpn := range w.Weapons {
+		if wpn.Def().Kind == "beam" {
+			w.CurrentWeapon = k
+		}
+	}
+	w.PowerUps[PiercingShots] = 1
+	w.Step(TickLength, InputState{Fire: true})
+
+	if w.Score != want {
+		t.Fatalf("scored %d, want %d", w.Score, want)
+	}
+
+}
+
+func TestDifficultyScalesLivesAndSpeed(t *testing.T) {
+
+	fastest := func(w *World) float64 {
+		top := 0.0
+		for _, e := range w.Entities {
+			if e.EntityType == Asteroid && e.velocity() > top {
+				top = e.velocity()
+			}
+		}
+		return top
+	}
+
+	config := DefaultConfig()
+	normal := NewWorld(1, config)
+	config.Difficulty = Easy
+	easy := NewWorld(1, config)
+	config.Difficulty = Hard
+	hard := NewWorld(1, config)
+
+	if !(easy.Lives > normal.Lives && normal.Lives > hard.Lives) {
+		t.Errorf("lives easy %d normal %d hard %d", easy.Lives, normal.Lives, hard.Lives)
+	}
+	if !(fastest(easy) < fastest(normal) && fastest(normal) < fastest(hard)) {
+		t.Errorf("top speeds easy %v normal %v hard %v", fastest(easy), fastest(normal), fastest(hard))
+	}
+
+	// a config saved before difficulties existed plays as normal
+	config.Difficulty = 0
+	if old := NewWorld(1, config); old.Lives != normal.Lives || fastest(old) != fastest(normal) {
+		t.Error("zero difficulty doesn't play as normal")
+	}
+
+}
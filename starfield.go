@@ -0,0 +1,164 @@
+package main
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"goasteroids/sim"
+	"math"
+	"math/rand"
+)
+
+// starLayer is one depth of the background, baked to a canvas once and then
+// tiled across the screen. Deeper layers have smaller parallax and so drift less
+// as the ship moves.
+type starLayer struct {
+	canvas   *pixelgl.Canvas
+	parallax float64
+	offset   pixel.Vec
+}
+
+type twinkler struct {
+	pos   pixel.Vec
+	layer int
+	phase float64
+	rate  float64
+	size  float64
+}
+
+var starLayers = []struct {
+	stars    int
+	size     float64
+	bright   float64
+	parallax float64
+}{
+	{stars: 300, size: 1, bright: 0.4, parallax: 0.02},
+	{stars: 120, size: 1.5, bright: 0.7, parallax: 0.05},
+	{stars: 40, size: 2, bright: 1, parallax: 0.1},
+}
+
+const (
+	nebulaClouds   = 6
+	nebulaParallax = 0.01
+	twinkleStars   = 30
+)
+
+var (
+	nebula    starLayer
+	layers    []starLayer
+	twinklers []twinkler
+	twinkle   = true
+	twinkles  = imdraw.New(nil)
+	starTime  float64
+)
+
+func newStarCanvas() *pixelgl.Canvas {
+
+	return pixelgl.NewCanvas(pixel.R(0, 0, sim.ScreenWidth, sim.ScreenHeight))
+
+}
+
+// initStarfield bakes the nebula and star layers from their own seeded source,
+// so a given seed always has the same sky without touching the world's draws.
+func initStarfield(seed int64) {
+
+	rng := rand.New(rand.NewSource(seed))
+	imd := imdraw.New(nil)
+
+	nebula = starLayer{canvas: newStarCanvas(), parallax: nebulaParallax}
+	tints := []pixel.RGBA{pixel.RGB(0.4, 0.1, 0.6), pixel.RGB(0.1, 0.2, 0.6), pixel.RGB(0.6, 0.1, 0.3)}
+	for k := 0; k < nebulaClouds; k++ {
+		centre := pixel.V(rng.Float64()*sim.ScreenWidth, rng.Float64()*sim.ScreenHeight)
+		tint := tints[rng.Intn(len(tints))]
+		radius := 120 + rng.Float64()*200
+		// stacked faint discs give each cloud a soft edge
+		for r := radius; r > 0; r -= radius / 12 {
+			imd.Color = tint.Mul(pixel.Alpha(0.025))
+			imd.Push(centre)
+			imd.Circle(r, 0)
+		}
+	}
+	imd.Draw(nebula.canvas)
+
+	layers = nil
+	for _, spec := range starLayers {
+		layer := starLayer{canvas: newStarCanvas(), parallax: spec.parallax}
+		imd.Clear()
+		for k := 0; k < spec.stars; k++ {
+			shade := spec.bright * (0.6 + 0.4*rng.Float64())
+			imd.Color = pixel.RGB(shade, shade, shade)
+			imd.Push(pixel.V(rng.Float64()*sim.ScreenWidth, rng.Float64()*sim.ScreenHeight))
+			imd.Circle(spec.size, 0)
+		}
+		imd.Draw(layer.canvas)
+		layers = append(layers, layer)
+	}
+
+	twinklers = make([]twinkler, twinkleStars)
+	for k := range twinklers {
+		twinklers[k] = twinkler{
+			pos:   pixel.V(rng.Float64()*sim.ScreenWidth, rng.Float64()*sim.ScreenHeight),
+			layer: rng.Intn(len(layers)),
+			phase: rng.Float64() * 2 * math.Pi,
+			rate:  1 + rng.Float64()*3,
+			size:  1 + rng.Float64(),
+		}
+	}
+
+}
+
+// updateStarfield drifts every layer against the ship's velocity.
+func updateStarfield(dt float64, w *sim.World) {
+
+	starTime += dt
+
+	ship := w.Entities[0]
+	drift := pixel.V(-ship.Dx, -ship.Dy).Scaled(dt)
+
+	nebula.offset = wrapOffset(nebula.offset.Add(drift.Scaled(nebula.parallax)))
+	for k := range layers {
+		layers[k].offset = wrapOffset(layers[k].offset.Add(drift.Scaled(layers[k].parallax)))
+	}
+
+}
+
+func wrapOffset(v pixel.Vec) pixel.Vec {
+
+	return pixel.V(math.Mod(v.X+sim.ScreenWidth, sim.ScreenWidth), math.Mod(v.Y+sim.ScreenHeight, sim.ScreenHeight))
+
+}
+
+// draw tiles the layer's canvas so that, wherever its offset has drifted to, the
+// whole screen is covered.
+func (l starLayer) draw() {
+
+	centre := pixel.V(sim.ScreenWidth/2, sim.ScreenHeight/2)
+	for _, dx := range []float64{0, -sim.ScreenWidth} {
+		for _, dy := range []float64{0, -sim.ScreenHeight} {
+			l.canvas.Draw(window, pixel.IM.Moved(centre.Add(l.offset).Add(pixel.V(dx, dy))))
+		}
+	}
+
+}
+
+func drawStarfield() {
+
+	nebula.draw()
+	for _, l := range layers {
+		l.draw()
+	}
+
+	if !twinkle {
+		return
+	}
+
+	twinkles.Clear()
+	for _, t := range twinklers {
+		shade := 0.5 + 0.5*math.Sin(starTime*t.rate+t.phase)
+		twinkles.Color = pixel.RGB(shade, shade, shade)
+		twinkles.Push(wrapOffset(t.pos.Add(layers[t.layer].offset)))
+		twinkles.Circle(t.size, 0)
+	}
+	twinkles.Draw(window)
+
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"goasteroids/sim"
+	"golang.org/x/image/colornames"
+	"os"
+	"time"
+)
+
+type gameState int
+
+const (
+	Title          gameState = 1
+	Playing        gameState = 2
+	Paused         gameState = 3
+	GameOver       gameState = 4
+	HighScoreEntry gameState = 5
+)
+
+var (
+	state       = Title
+	accumulator float64
+	// fixedSeed is set when -seed was given, so every new game replays the same
+	// field; otherwise each one starts from a fresh seed
+	fixedSeed      bool
+	highScores     *HighScores
+	highScoresFile string
+	initials       initialsEntry
+	// initialsReady waits for every game key to be let go, so letters still held
+	// down from play can't repeat into the initials
+	initialsReady bool
+)
+
+// loadScores reads the high score tables from the user's config directory. If
+// there isn't one, scores are still kept for this session but never saved.
+func loadScores() {
+
+	path, err := highScoresPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	highScoresFile = path
+	highScores = loadHighScores(path)
+
+}
+
+func saveScores() {
+
+	if highScoresFile == "" {
+		return
+	}
+	if err := highScores.save(highScoresFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+}
+
+// newGame throws away the current world and starts another in the same window,
+// along with a fresh recording of it.
+func newGame() {
+
+	if playback != nil {
+		seed = playback.Seed
+		config = playback.Config
+	} else if !fixedSeed {
+		seed = time.Now().UnixNano()
+	}
+
+	world = sim.NewWorld(seed, config)
+	initParticles(seed)
+	initStarfield(seed)
+	effects = nil
+
+	recording = &sim.Replay{Version: sim.ReplayVersion, Seed: seed, Config: config}
+	accumulator = 0
+
+	window.SetTitle(fmt.Sprintf("%s | Seed: %d", windowTitlePrefix, seed))
+
+}
+
+// step runs the world on by however many whole ticks have built up. Only games
+// being played are recorded; a finished world just drifts on without controls.
+func step(live sim.InputState, record bool) {
+
+	accumulator += frameLength
+
+	for accumulator >= sim.TickLength {
+		input := sim.InputState{}
+		if record {
+			var ok bool
+			input, ok = playback.Input(len(recording.Inputs))
+			if !ok {
+				input = live
+			}
+			recording.Record(input)
+		}
+		world.Step(sim.TickLength, input)
+		startEffects(world.Events)
+		startParticles(world)
+		accumulator -= sim.TickLength
+	}
+
+	updateEffects(frameLength)
+	updateParticles(frameLength)
+	updateStarfield(frameLength, world)
+
+}
+
+// update handles the keys that move between states and advances whatever the
+// current state keeps running. Nothing moves while paused, so the world's timers
+// and cooldowns all wait for it to resume.
+func update() {
+
+	pause := window.JustPressed(pixelgl.KeyP) || window.JustPressed(pixelgl.KeyEscape)
+
+	switch state {
+
+	case Title:
+		updateStarfield(frameLength, world)
+		if window.JustPressed(pixelgl.KeyEnter) {
+			newGame()
+			state = Playing
+		}
+
+	case Playing:
+		if pause {
+			state = Paused
+			return
+		}
+		step(readInput(), true)
+		if !world.GameOver {
+			return
+		}
+		state = GameOver
+		// replays are just being watched, so they never go in the table
+		if playback == nil && highScores.qualifies(tableKey(world.Config), world.Score) {
+			initials = newInitialsEntry()
+			initialsReady = false
+			state = HighScoreEntry
+		}
+
+	case Paused:
+		if pause {
+			state = Playing
+		} else if window.JustPressed(pixelgl.KeyR) {
+			newGame()
+			state = Playing
+		} else if window.JustPressed(pixelgl.KeyQ) {
+			state = Title
+		}
+
+	case HighScoreEntry:
+		step(sim.InputState{}, false)
+		if !initialsReady {
+			initialsReady = readInput() == sim.InputState{}
+			return
+		}
+		switch {
+		case window.JustPressed(pixelgl.KeyUp):
+			initials.roll(1)
+		case window.JustPressed(pixelgl.KeyDown):
+			initials.roll(-1)
+		case window.JustPressed(pixelgl.KeyLeft), window.JustPressed(pixelgl.KeyBackspace):
+			initials.move(-1)
+		case window.JustPressed(pixelgl.KeyRight):
+			initials.move(1)
+		case window.JustPressed(pixelgl.KeyEnter):
+			highScores.add(tableKey(world.Config), HighScore{
+				Initials: initials.String(),
+				Score:    world.Score,
+				Wave:     world.Wave,
+				Date:     time.Now(),
+			})
+			saveScores()
+			state = GameOver
+		default:
+			initials.typed(window.Typed())
+		}
+
+	case GameOver:
+		step(sim.InputState{}, false)
+		if window.JustPressed(pixelgl.KeyEnter) {
+			newGame()
+			state = Playing
+		} else if window.JustPressed(pixelgl.KeyEscape) {
+			state = Title
+		}
+
+	}
+
+}
+
+func drawState() {
+
+	if state == Title {
+		drawStarfield()
+		drawBanner("GO ASTEROIDS")
+		drawPrompt("PRESS ENTER TO PLAY")
+		drawScoreTable(highScores.table(tableKey(config)))
+		return
+	}
+
+	draw(world, accumulator/sim.TickLength)
+
+	switch state {
+	case Paused:
+		drawBanner("PAUSED")
+		drawPrompt("P TO RESUME   R TO RESTART   Q TO QUIT")
+	case HighScoreEntry:
+		drawBanner("NEW HIGH SCORE")
+		drawPrompt(initials.display())
+	case GameOver:
+		drawBanner("GAME OVER")
+		drawPrompt("ENTER TO PLAY AGAIN   ESC FOR TITLE")
+		drawScoreTable(highScores.table(tableKey(world.Config)))
+	}
+
+}
+
+// drawPrompt writes a line of small text centred under the banner.
+func drawPrompt(message string) {
+
+	hudText(pixel.V((sim.ScreenWidth-textWidth(message)*2)/2, sim.ScreenHeight/2-40), 2, colornames.Lightsteelblue, "%s", message)
+
+}
+
+// drawScoreTable lists a high score table down the lower half of the screen.
+func drawScoreTable(table []HighScore) {
+
+	for k, s := range table {
+		line := fmt.Sprintf("%2d  %s  %08d  WAVE %d", k+1, s.Initials, s.Score, s.Wave)
+		hudText(pixel.V((sim.ScreenWidth-textWidth(line)*1.5)/2, sim.ScreenHeight/2-90-float64(k)*20), 1.5, colornames.White, "%s", line)
+	}
+
+}